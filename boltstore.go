@@ -0,0 +1,562 @@
+package elecciones
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Buckets whose name starts with metaBucketPrefix hold bookkeeping data
+// instead of the snapshots of a node.
+const metaBucketPrefix = "_"
+
+var (
+	// seenBucket records, per node path, the last time a snapshot was
+	// retrieved even if it was not stored because it did not change.
+	seenBucket = []byte(metaBucketPrefix + "seen")
+
+	// validatorsBucket holds, per node path, the cache validators of the
+	// last body stored.
+	validatorsBucket = []byte(metaBucketPrefix + "validators")
+
+	// updatesBucket holds, in a bucket per node path, the index of the
+	// snapshots by the time of the update published by the ministry.
+	updatesBucket = []byte(metaBucketPrefix + "updates")
+
+	// responsesBucket holds, in a bucket per node path, the Response stored
+	// alongside every snapshot, keyed like the snapshot.
+	responsesBucket = []byte(metaBucketPrefix + "responses")
+
+	// quarantineBucket holds the payloads that failed validation.
+	quarantineBucket = []byte(metaBucketPrefix + "quarantine")
+)
+
+// IsNodeBucket reports whether the top-level bucket name holds the snapshots
+// of a node.
+func IsNodeBucket(name []byte) bool {
+	return !bytes.HasPrefix(name, []byte(metaBucketPrefix))
+}
+
+// BoltStore is a Store backed by a bolt database. The snapshots of every node
+// are kept in a bucket named after the node path, keyed by the binary
+// encoding of their time and compressed with encodeEntry.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore returns a Store that keeps its data in db.
+func NewBoltStore(db *bolt.DB) *BoltStore {
+	return &BoltStore{db: db}
+}
+
+// OpenBoltStore opens the bolt database in filename, creating it if it does
+// not exist and opts allows it.
+func OpenBoltStore(filename string, opts *bolt.Options) (*BoltStore, error) {
+	db, err := bolt.Open(filename, 0600, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewBoltStore(db), nil
+}
+
+func (s *BoltStore) Put(path string, t time.Time, data []byte) (bool, error) {
+	n, err := s.PutAll(path, []RawSnapshot{{Time: t, Data: data}})
+	return n > 0, err
+}
+
+func (s *BoltStore) PutAll(path string, snapshots []RawSnapshot) (int, error) {
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+
+	var stored int
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(path))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+
+		var previous []byte
+		if _, last := b.Cursor().Last(); last != nil {
+			if data, err := DecodeEntry(last); err == nil {
+				previous = data
+			}
+		}
+
+		var key []byte
+		for _, snapshot := range snapshots {
+			key, err = snapshot.Time.MarshalBinary()
+			if err != nil {
+				return err
+			}
+
+			if previous != nil && bytes.Equal(previous, snapshot.Data) {
+				continue
+			}
+			previous = snapshot.Data
+
+			value, err := encodeEntry(snapshot.Data)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, value); err != nil {
+				return err
+			}
+			if snapshot.Response != nil {
+				if err := putResponse(tx, path, key, *snapshot.Response); err != nil {
+					return err
+				}
+			}
+			stored++
+		}
+
+		return markSeen(tx, path, key)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	return stored, nil
+}
+
+func putResponse(tx *bolt.Tx, path string, key []byte, resp Response) error {
+	responses, err := tx.CreateBucketIfNotExists(responsesBucket)
+	if err != nil {
+		return fmt.Errorf("create bucket: %s", err)
+	}
+
+	b, err := responses.CreateBucketIfNotExists([]byte(path))
+	if err != nil {
+		return fmt.Errorf("create bucket: %s", err)
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	value, err := encodeEntry(data)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, value)
+}
+
+func (s *BoltStore) Parsed(path string, t time.Time) (Response, error) {
+	var resp Response
+
+	key, err := t.MarshalBinary()
+	if err != nil {
+		return resp, err
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		responses := tx.Bucket(responsesBucket)
+		if responses == nil {
+			return ErrUnknownPath
+		}
+
+		b := responses.Bucket([]byte(path))
+		if b == nil {
+			return ErrUnknownPath
+		}
+
+		v := b.Get(key)
+		if v == nil {
+			return ErrUnknownPath
+		}
+
+		data, err := DecodeEntry(v)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &resp)
+	})
+
+	return resp, err
+}
+
+func (s *BoltStore) Touch(path string, t time.Time) error {
+	key, err := t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return markSeen(tx, path, key)
+	})
+}
+
+func markSeen(tx *bolt.Tx, path string, key []byte) error {
+	b, err := tx.CreateBucketIfNotExists(seenBucket)
+	if err != nil {
+		return fmt.Errorf("create bucket: %s", err)
+	}
+	return b.Put([]byte(path), key)
+}
+
+func (s *BoltStore) LastSeen(path string) (time.Time, error) {
+	var t time.Time
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(seenBucket)
+		if b == nil {
+			return ErrUnknownPath
+		}
+
+		v := b.Get([]byte(path))
+		if v == nil {
+			return ErrUnknownPath
+		}
+
+		return t.UnmarshalBinary(v)
+	})
+
+	return t, err
+}
+
+func (s *BoltStore) Latest(path string, at time.Time) (time.Time, []byte, error) {
+	var (
+		t    time.Time
+		data []byte
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(path))
+		if b == nil || !IsNodeBucket([]byte(path)) {
+			return ErrUnknownPath
+		}
+
+		c := b.Cursor()
+		k, v := c.Last()
+		if !at.IsZero() {
+			key, err := at.MarshalBinary()
+			if err != nil {
+				return err
+			}
+
+			// Seek positions the cursor on the first entry at or after at.
+			k, v = c.Seek(key)
+			if k == nil {
+				k, v = c.Last()
+			}
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			if err := t.UnmarshalBinary(k); err != nil {
+				log.Printf("Skipping entry of %s: %v\n", path, err)
+				continue
+			}
+			if !at.IsZero() && t.After(at) {
+				continue
+			}
+
+			var err error
+			data, err = DecodeEntry(v)
+			if err != nil {
+				log.Printf("Skipping entry of %s: %v\n", path, err)
+				continue
+			}
+
+			return nil
+		}
+
+		return ErrUnknownPath
+	})
+
+	return t, data, err
+}
+
+func (s *BoltStore) Range(path string, from, to time.Time, fn func(t time.Time, data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(path))
+		if b == nil || !IsNodeBucket([]byte(path)) {
+			return ErrUnknownPath
+		}
+
+		c := b.Cursor()
+		k, v := c.First()
+		if !from.IsZero() {
+			start, err := from.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			k, v = c.Seek(start)
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			var t time.Time
+			if err := t.UnmarshalBinary(k); err != nil {
+				log.Printf("Skipping entry of %s: %v\n", path, err)
+				continue
+			}
+
+			if t.Before(from) {
+				continue
+			}
+			if !to.IsZero() && t.After(to) {
+				break
+			}
+
+			data, err := DecodeEntry(v)
+			if err != nil {
+				log.Printf("Skipping entry of %s: %v\n", path, err)
+				continue
+			}
+
+			if err := fn(t, data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) DeleteAll(path string, times []time.Time) error {
+	if len(times) == 0 {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(path))
+		if b == nil || !IsNodeBucket([]byte(path)) {
+			return nil
+		}
+
+		var parsed *bolt.Bucket
+		if responses := tx.Bucket(responsesBucket); responses != nil {
+			parsed = responses.Bucket([]byte(path))
+		}
+
+		for _, t := range times {
+			key, err := t.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+			if parsed == nil {
+				continue
+			}
+			if err := parsed.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) Paths() ([]string, error) {
+	var out []string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if IsNodeBucket(name) {
+				out = append(out, string(name))
+			}
+			return nil
+		})
+	})
+
+	return out, err
+}
+
+func (s *BoltStore) Stats() (map[string]int, error) {
+	out := make(map[string]int)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if IsNodeBucket(name) {
+				out[string(name)] = b.Stats().KeyN
+			}
+			return nil
+		})
+	})
+
+	return out, err
+}
+
+// Backup writes a consistent copy of the bolt database to w.
+func (s *BoltStore) Backup(w io.Writer) error {
+	return s.BackupSized(w, func(int64) {})
+}
+
+// BackupSized is like Backup, but first calls size with the size of the
+// copy.
+func (s *BoltStore) BackupSized(w io.Writer, size func(int64)) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		size(tx.Size())
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+func (s *BoltStore) Validators(path string) (Validators, error) {
+	var v Validators
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(validatorsBucket)
+		if b == nil {
+			return nil
+		}
+
+		data := b.Get([]byte(path))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &v)
+	})
+
+	return v, err
+}
+
+func (s *BoltStore) SetValidators(path string, v Validators) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(validatorsBucket)
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		return b.Put([]byte(path), data)
+	})
+}
+
+func (s *BoltStore) Index(path string, entries ...IndexEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		updates, err := tx.CreateBucketIfNotExists(updatesBucket)
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+
+		b, err := updates.CreateBucketIfNotExists([]byte(path))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+
+		for _, e := range entries {
+			key, err := e.Source.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			if b.Get(key) != nil {
+				continue
+			}
+
+			value, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) Updates(path string) ([]IndexEntry, error) {
+	var out []IndexEntry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		updates := tx.Bucket(updatesBucket)
+		if updates == nil {
+			return ErrUnknownPath
+		}
+
+		b := updates.Bucket([]byte(path))
+		if b == nil {
+			return ErrUnknownPath
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var e IndexEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			out = append(out, e)
+			return nil
+		})
+	})
+
+	return out, err
+}
+
+func (s *BoltStore) Quarantine(e QuarantineEntry) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	key, err := e.Time.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	// Several nodes may fail at the same time.
+	key = append(key, []byte(e.Path)...)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(quarantineBucket)
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		if err := b.Put(key, value); err != nil {
+			return err
+		}
+
+		// Stats does not count the keys put in this transaction.
+		n := 0
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			n++
+		}
+		for ; n > MaxQuarantined; n-- {
+			c.First()
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) Quarantined() ([]QuarantineEntry, error) {
+	var out []QuarantineEntry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(quarantineBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var e QuarantineEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			out = append(out, e)
+			return nil
+		})
+	})
+
+	return out, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
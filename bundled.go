@@ -0,0 +1,30 @@
+package elecciones
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"path"
+)
+
+//go:embed data/pais.json data/comunidad.json data/provincia.json data/islas.json data/municipio.json data/distrito.json
+var bundledConfig embed.FS
+
+// BundledConfigSource reads the configuration files bundled with the package
+// in data/, those of the December 2015 Congreso election. It lets the
+// commands that only read a stored db work offline.
+type BundledConfigSource struct{}
+
+func (BundledConfigSource) Open(name string) (io.ReadCloser, error) {
+	return bundledConfig.Open(path.Join("data", name))
+}
+
+// LoadBundledConfig builds the Config of the given election from the
+// configuration files bundled with the package. Only the files of
+// Congreso2015 are bundled.
+func LoadBundledConfig(e Election) (*Config, error) {
+	if e.Code != Congreso2015.Code {
+		return nil, fmt.Errorf("no configuration bundled for election %s", e.Code)
+	}
+	return LoadConfigFrom(BundledConfigSource{}, e)
+}
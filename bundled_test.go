@@ -0,0 +1,29 @@
+package elecciones
+
+import "testing"
+
+func TestLoadBundledConfig(t *testing.T) {
+	bundled, err := LoadBundledConfig(Congreso2015)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := LoadConfigFromDir("data", Congreso2015)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(c *Config) int {
+		n := 0
+		for range c.Walk() {
+			n++
+		}
+		return n
+	}
+	if b, d := count(bundled), count(dir); b != d {
+		t.Errorf("bundled config has %d nodes, data/ has %d", b, d)
+	}
+
+	if _, err := LoadBundledConfig(Congreso2016); err == nil {
+		t.Error("expected an error for an election without bundled configuration")
+	}
+}
@@ -4,26 +4,42 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/boltdb/bolt"
 	"github.com/mgilbir/elecciones"
+	"github.com/mgilbir/elecciones/cmd/internal/settings"
 )
 
 var (
-	filename = flag.String("db", "congreso20D2015.db", "db filename")
-	port     = flag.String("http", ":8081", "the port where the server is listening")
+	filename  = settings.DB(flag.CommandLine)
+	port      = settings.HTTP(flag.CommandLine, ":8081")
+	territory = settings.ElectionFlags(flag.CommandLine)
 )
 
 func main() {
-	flag.Parse()
+	if err := settings.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := elecciones.OpenBoltStore(*filename, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
 
-	db, err := bolt.Open(*filename, 0666, &bolt.Options{ReadOnly: true})
+	conf, err := territory.LoadOfflineConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
-	http.Handle("/stats", elecciones.NewStatsHandler(db))
+	http.Handle("/stats", elecciones.NewStatsHandler(store))
+	http.Handle("/results/", http.StripPrefix("/results/", elecciones.NewReadHandler(store)))
+	http.Handle("/history/", http.StripPrefix("/history/", elecciones.NewHistoryHandler(store)))
+	http.Handle("/updates/", http.StripPrefix("/updates/", elecciones.NewUpdatesHandler(store)))
+	http.Handle("/quarantine", elecciones.NewQuarantineHandler(store))
+	http.Handle("/at", elecciones.NewStateHandler(conf, store))
+	http.Handle("/territories/search", elecciones.NewTerritorySearchHandler(conf))
 
 	log.Fatal(http.ListenAndServe(*port, nil))
 }
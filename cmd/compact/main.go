@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/boltdb/bolt"
+	"github.com/mgilbir/elecciones"
+	"github.com/mgilbir/elecciones/cmd/internal/settings"
+)
+
+var (
+	filename  = settings.DB(flag.CommandLine)
+	output    = flag.String("out", "congreso20D2015.compact.db", "compacted db filename")
+	territory = settings.ElectionFlags(flag.CommandLine)
+	retention = settings.RetentionFlags(flag.CommandLine)
+)
+
+func main() {
+	if err := settings.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	policy, err := retention.Policy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var conf *elecciones.Config
+	if policy != nil {
+		conf, err = territory.LoadOfflineConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(*output); err == nil {
+		log.Fatalf("%s already exists", *output)
+	}
+
+	src, err := elecciones.OpenBoltStore(*filename, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer src.Close()
+
+	dst, err := elecciones.OpenBoltStore(*output, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dst.Close()
+
+	stats, err := elecciones.Compact(src, dst)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Compacted %d nodes: kept %d snapshots, dropped %d duplicates\n", stats.Paths, stats.Kept, stats.Dropped)
+
+	if policy == nil {
+		return
+	}
+
+	retained, err := policy.Apply(conf, dst)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Applied retention to %d nodes: kept %d snapshots, deleted %d\n", retained.Paths, retained.Kept, retained.Deleted)
+}
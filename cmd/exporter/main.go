@@ -3,49 +3,71 @@ package main
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/mgilbir/elecciones"
+	"github.com/mgilbir/elecciones/cmd/internal/settings"
 )
 
 var (
-	filename = flag.String("db", "congreso20D2015.db", "db filename")
-	port     = flag.String("http", ":8081", "the port where the server is listening")
-	output   = flag.String("out", "congreso20D2015.csv", "output filename")
+	filename  = settings.DB(flag.CommandLine)
+	output    = flag.String("out", "congreso20D2015.csv", "output filename")
+	territory = settings.TerritoryFlags(flag.CommandLine)
+	at        = flag.String("at", "", "export the last snapshot of every node at this time (RFC3339) instead of their whole history")
 )
 
 func main() {
-	flag.Parse()
+	if err := settings.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
 
-	db, err := bolt.Open(*filename, 0666, &bolt.Options{ReadOnly: true})
+	store, err := elecciones.OpenBoltStore(*filename, &bolt.Options{ReadOnly: true})
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
+	defer store.Close()
 
-	partiesAcronyms := make(map[string]struct{})
+	conf, err := territory.LoadOfflineConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *at != "" {
+		exportState(store, conf)
+		return
+	}
 
-	db.View(func(tx *bolt.Tx) error {
-		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
-			_, v := b.Cursor().First()
+	paths, err := store.Paths()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	partiesAcronyms := make(map[string]struct{})
 
+	for _, path := range paths {
+		err := store.Range(path, time.Time{}, time.Time{}, func(_ time.Time, data []byte) error {
 			var resp elecciones.Response
-			err := json.Unmarshal(v, &resp)
-			if err != nil {
-				return fmt.Errorf("ERROR parsing bucket %s", name)
+			if err := json.Unmarshal(data, &resp); err != nil {
+				return fmt.Errorf("ERROR parsing bucket %s", path)
 			}
 			for _, p := range resp.Results.Parties {
 				partiesAcronyms[p.Acronym] = struct{}{}
 			}
 
-			return nil
+			return errFirstOnly
 		})
-	})
+		if err != nil && err != errFirstOnly {
+			log.Println(err)
+		}
+	}
 
 	var headerPartyOrder []string
 	for k, _ := range partiesAcronyms {
@@ -71,74 +93,130 @@ func main() {
 		log.Fatal(err)
 	}
 
-	err = db.View(func(tx *bolt.Tx) error {
-		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
-			return b.ForEach(func(k []byte, v []byte) error {
-				var resp elecciones.Response
-				err := json.Unmarshal(v, &resp)
-				if err != nil {
-					log.Printf("ERROR parsing bucket %s. %v\n", name, err)
-				} else {
+	for _, path := range paths {
+		var location []string
+		if n := conf.ByPath(path); n != nil {
+			location = getLocationArray(n)
+		} else {
+			log.Printf("Unknown node for bucket %s, deriving its location from the path\n", path)
+			location = getLocationArrayForPath(path)
+		}
 
-					out := getLocationArrayForBucketName(string(name))
-					out = append(out, resp.ExportCurrentToCsv(headerPartyOrder, true, true, true)...)
+		snapshots, err := elecciones.History(store, path, time.Time{}, time.Time{})
+		if err != nil {
+			log.Printf("ERROR reading bucket %s. %v\n", path, err)
+			continue
+		}
 
-					err = w.Write(out)
-					if err != nil {
-						log.Fatal(err)
-					}
-				}
+		for _, s := range snapshots {
+			out := append([]string{}, location...)
+			out = append(out, s.Response.ExportCurrentToCsv(headerPartyOrder, true, true, true)...)
 
-				return nil
-			})
-		})
-	})
-	log.Println(err)
+			err = w.Write(out)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
 }
 
-func getLocationArrayForBucketName(name string) []string {
-	out := make([]string, 6)
+// exportState writes one row per node with its last snapshot at -at.
+func exportState(store elecciones.Store, conf *elecciones.Config) {
+	t, err := time.Parse(time.RFC3339, *at)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	b := strings.Split(name, "/")
+	walk, err := territory.WalkOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	walk.Order = elecciones.OrderDepthFirst
 
-	out[0] = b[0]
+	var nodes []*elecciones.Node
+	var paths []string
+	for n := range conf.WalkWith(walk) {
+		nodes = append(nodes, n)
+		paths = append(paths, n.Path())
+	}
 
-	if len(b) > 1 {
-		out[1] = b[1]
+	state, err := elecciones.StateOf(store, paths, t)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	partiesAcronyms := make(map[string]struct{})
+	for _, s := range state {
+		for _, p := range s.Response.Results.Parties {
+			partiesAcronyms[p.Acronym] = struct{}{}
+		}
+	}
+
+	var headerPartyOrder []string
+	for k := range partiesAcronyms {
+		headerPartyOrder = append(headerPartyOrder, k)
 	}
+	sort.Strings(headerPartyOrder)
 
-	if len(b) > 2 {
-		out[2] = b[2]
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer f.Close()
 
-	var isIsland bool
-	if len(b) > 3 {
-		if b[2] == "07" || b[2] == "35" || b[2] == "38" {
-			isIsland = true
+	w := csv.NewWriter(f)
+	w.Comma = ';'
+	defer w.Flush()
+
+	headers := []string{"Pais", "Comunidad", "Provincia", "Isla", "Municipio", "Distrito"}
+	headers = append(headers, elecciones.Response{}.GetCsvHeaders(headerPartyOrder, true, true, true)...)
+	if err := w.Write(headers); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, n := range nodes {
+		s, ok := state[n.Path()]
+		if !ok {
+			continue
 		}
 
-		if isIsland {
-			out[3] = b[3]
-		} else {
-			out[4] = b[3]
+		out := getLocationArray(n)
+		out = append(out, s.Response.ExportCurrentToCsv(headerPartyOrder, true, true, true)...)
+		if err := w.Write(out); err != nil {
+			log.Fatal(err)
 		}
 	}
+}
 
-	if len(b) > 4 {
-		if isIsland {
-			out[4] = b[4]
-		} else {
-			out[5] = b[4]
-		}
+// errFirstOnly stops a Range after its first snapshot.
+var errFirstOnly = errors.New("first snapshot only")
+
+// getLocationArray returns the IDs of the node and its ancestors, each one in
+// the column of its level.
+func getLocationArray(n *elecciones.Node) []string {
+	out := make([]string, len(elecciones.Levels))
+	for _, a := range append(n.Ancestors(), n) {
+		out[a.Level()] = a.ID()
 	}
+	return out
+}
 
-	if len(b) > 5 {
-		if isIsland {
-			out[5] = b[5]
-		} else {
-			out[6] = b[5]
+// getLocationArrayForPath is getLocationArray for the paths that are not in
+// the territorial configuration. Below the provincia, the level of every ID is
+// guessed from its length: islas have 3 digits, municipios 5 and distritos 7.
+func getLocationArrayForPath(path string) []string {
+	out := make([]string, len(elecciones.Levels))
+	for i, id := range strings.Split(path, "/") {
+		switch {
+		case i <= int(elecciones.LevelProvincia):
+			out[i] = id
+		case len(id) == 3:
+			out[elecciones.LevelIsla] = id
+		case len(id) == 5:
+			out[elecciones.LevelMunicipio] = id
+		default:
+			out[elecciones.LevelDistrito] = id
 		}
 	}
-
 	return out
 }
@@ -1,20 +1,34 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"os"
 
-	"github.com/mgilbir/elecciones"
+	"github.com/mgilbir/elecciones/cmd/internal/settings"
+)
+
+var (
+	territory = settings.TerritoryFlags(flag.CommandLine)
 )
 
-//TODO: Add flags to configure filename and refresh time
 func main() {
-	conf, err := elecciones.LoadConfig()
+	if err := settings.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	walk, err := territory.WalkOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conf, err := territory.LoadConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	for n := range conf.Walk() {
-		fmt.Println(n.URL())
+	for n := range conf.WalkWith(walk) {
+		fmt.Println(n.URL(conf.Election()))
 	}
 }
@@ -0,0 +1,231 @@
+// Package settings gives the commands a consistent set of flags, which can
+// also be set through environment variables and a JSON config file.
+package settings
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mgilbir/elecciones"
+)
+
+// EnvPrefix prefixes the environment variable of every flag, e.g.
+// ELECCIONES_DB for -db.
+const EnvPrefix = "ELECCIONES_"
+
+const DefaultDB = "congreso20D2015.db"
+
+// Parse parses args into fs. The flags not given in args are taken from the
+// environment or, failing that, from the JSON config file named by -config
+// (or ELECCIONES_CONFIG), an object mapping flag names to values:
+//
+//	{"db": "congreso26J2016.db", "election": "ES201606-CON-ES", "interval": "2m"}
+func Parse(fs *flag.FlagSet, args []string) error {
+	config := fs.String("config", "", "JSON file with values for the flags not given in the command line")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || err != nil {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			if err = fs.Set(f.Name, v); err != nil {
+				err = fmt.Errorf("%s: %v", envName(f.Name), err)
+				return
+			}
+			set[f.Name] = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if *config == "" {
+		return nil
+	}
+	return loadConfigFile(fs, *config, set)
+}
+
+// IsSet reports whether the flag name of fs was given, in the command line,
+// the environment or the config file. It must be called after Parse.
+func IsSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+func loadConfigFile(fs *flag.FlagSet, filename string, set map[string]bool) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Keep numbers as written, as the float64 of a large integer such as
+	// 1000000 prints as 1e+06, which integer flags do not accept.
+	d := json.NewDecoder(f)
+	d.UseNumber()
+
+	var values map[string]interface{}
+	if err := d.Decode(&values); err != nil {
+		return fmt.Errorf("parsing %s: %v", filename, err)
+	}
+
+	for name, v := range values {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown flag %q", filename, name)
+		}
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("%s: %s: %v", filename, name, err)
+		}
+	}
+
+	return nil
+}
+
+// DB registers the -db flag.
+func DB(fs *flag.FlagSet) *string {
+	return fs.String("db", DefaultDB, "db filename")
+}
+
+// HTTP registers the -http flag with the given default address.
+func HTTP(fs *flag.FlagSet, addr string) *string {
+	return fs.String("http", addr, "the address where the server is listening")
+}
+
+// Territory holds the flags that select the election and the nodes of its
+// territorial tree.
+type Territory struct {
+	election  *string
+	configDir *string
+	levels    *string
+	root      *string
+	maxDepth  *int
+}
+
+// TerritoryFlags registers the -election, -configdir, -levels, -root and
+// -maxdepth flags.
+func TerritoryFlags(fs *flag.FlagSet) Territory {
+	t := ElectionFlags(fs)
+	t.levels = fs.String("levels", "", "comma separated levels to include (pais, comunidad, provincia, isla, municipio, distrito); all by default")
+	t.root = fs.String("root", "", "only include the subtree of the node with this path, e.g. ES/CA13")
+	t.maxDepth = fs.Int("maxdepth", 0, "only include this many levels below -root (0 for no limit)")
+	return t
+}
+
+// ElectionFlags registers the -election and -configdir flags, for the
+// commands that need the territorial configuration but do not walk it.
+func ElectionFlags(fs *flag.FlagSet) Territory {
+	return Territory{
+		election:  fs.String("election", elecciones.DefaultElection.Code, "election code or path to an election descriptor file"),
+		configDir: fs.String("configdir", "", "load the territorial configuration from this directory instead of the ministry server (or of the bundled copy, for the commands that only read a db)"),
+	}
+}
+
+// Election returns the election selected with -election.
+func (t Territory) Election() (elecciones.Election, error) {
+	return elecciones.ParseElection(*t.election)
+}
+
+// LoadConfig loads the territorial configuration of the selected election,
+// from -configdir if given or from the ministry server otherwise.
+func (t Territory) LoadConfig() (*elecciones.Config, error) {
+	e, err := t.Election()
+	if err != nil {
+		return nil, err
+	}
+
+	if *t.configDir != "" {
+		return elecciones.LoadConfigFromDir(*t.configDir, e)
+	}
+	return elecciones.LoadConfig(e)
+}
+
+// LoadOfflineConfig is LoadConfig for the commands that only read a stored
+// db: without -configdir it uses the configuration bundled with the package
+// instead of the ministry server.
+func (t Territory) LoadOfflineConfig() (*elecciones.Config, error) {
+	e, err := t.Election()
+	if err != nil {
+		return nil, err
+	}
+
+	if *t.configDir != "" {
+		return elecciones.LoadConfigFromDir(*t.configDir, e)
+	}
+	return elecciones.LoadBundledConfig(e)
+}
+
+// WalkOptions returns the nodes selected with -levels, -root and -maxdepth,
+// or every node if t was registered with ElectionFlags.
+func (t Territory) WalkOptions() (elecciones.WalkOptions, error) {
+	if t.levels == nil {
+		return elecciones.WalkOptions{}, nil
+	}
+
+	levels, err := elecciones.ParseLevels(*t.levels)
+	if err != nil {
+		return elecciones.WalkOptions{}, err
+	}
+
+	return elecciones.WalkOptions{
+		Levels:   levels,
+		Root:     strings.Trim(*t.root, "/"),
+		MaxDepth: *t.maxDepth,
+	}, nil
+}
+
+// Retention holds the flags that configure the retention of snapshots.
+type Retention struct {
+	enabled  *bool
+	keepAll  *string
+	interval *time.Duration
+}
+
+// RetentionFlags registers the -retain, -keepall and -downsample flags.
+func RetentionFlags(fs *flag.FlagSet) Retention {
+	return Retention{
+		enabled:  fs.Bool("retain", false, "apply the retention policy to the stored snapshots"),
+		keepAll:  fs.String("keepall", "pais,comunidad,provincia", "comma separated levels whose snapshots are all kept"),
+		interval: fs.Duration("downsample", elecciones.DefaultRetentionPolicy.Interval, "keep one snapshot per this interval of the other levels once their count is final (0 to keep them all)"),
+	}
+}
+
+// Policy returns the retention policy selected with -keepall and -downsample,
+// or nil if -retain is not set.
+func (r Retention) Policy() (*elecciones.RetentionPolicy, error) {
+	if !*r.enabled {
+		return nil, nil
+	}
+
+	levels, err := elecciones.ParseLevels(*r.keepAll)
+	if err != nil {
+		return nil, err
+	}
+
+	return &elecciones.RetentionPolicy{KeepAll: levels, Interval: *r.interval}, nil
+}
@@ -0,0 +1,101 @@
+package settings
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePrecedence(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	err := ioutil.WriteFile(config, []byte(`{"db": "file.db", "http": ":9000", "interval": "2m", "concurrency": 4}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ELECCIONES_HTTP", ":9001")
+	t.Setenv("ELECCIONES_CONCURRENCY", "8")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	db := DB(fs)
+	addr := HTTP(fs, ":8080")
+	interval := fs.Duration("interval", 5*time.Minute, "")
+	concurrency := fs.Int("concurrency", 16, "")
+	retries := fs.Int("retries", 3, "")
+
+	if err := Parse(fs, []string{"-config", config, "-concurrency", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if *db != "file.db" {
+		t.Errorf("db = %s, want the config file value", *db)
+	}
+	if *addr != ":9001" {
+		t.Errorf("http = %s, want the environment value", *addr)
+	}
+	if *interval != 2*time.Minute {
+		t.Errorf("interval = %v, want the config file value", *interval)
+	}
+	if *concurrency != 2 {
+		t.Errorf("concurrency = %d, want the command line value", *concurrency)
+	}
+	if *retries != 3 {
+		t.Errorf("retries = %d, want the default", *retries)
+	}
+}
+
+func TestParseUnknownConfigFlag(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(config, []byte(`{"colour": "blue"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	DB(fs)
+
+	if err := Parse(fs, []string{"-config", config}); err == nil {
+		t.Error("expected an error for an unknown flag in the config file")
+	}
+}
+
+func TestParseConfigNumbers(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(config, []byte(`{"max": 1000000, "rps": 2.5}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	max := fs.Int("max", 0, "")
+	rps := fs.Float64("rps", 0, "")
+
+	if err := Parse(fs, []string{"-config", config}); err != nil {
+		t.Fatal(err)
+	}
+	if *max != 1000000 {
+		t.Errorf("max = %d, want 1000000", *max)
+	}
+	if *rps != 2.5 {
+		t.Errorf("rps = %v, want 2.5", *rps)
+	}
+}
+
+func TestIsSet(t *testing.T) {
+	t.Setenv("ELECCIONES_RETRIES", "5")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	DB(fs)
+	fs.Int("retries", 3, "")
+	fs.Duration("deadline", time.Minute, "")
+
+	if err := Parse(fs, []string{"-db", "file.db"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]bool{"db": true, "retries": true, "deadline": false} {
+		if got := IsSet(fs, name); got != want {
+			t.Errorf("IsSet(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
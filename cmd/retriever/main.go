@@ -1,38 +1,128 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/boltdb/bolt"
 	"github.com/mgilbir/elecciones"
+	"github.com/mgilbir/elecciones/cmd/internal/settings"
+)
+
+var (
+	filename    = settings.DB(flag.CommandLine)
+	port        = settings.HTTP(flag.CommandLine, ":8080")
+	territory   = settings.TerritoryFlags(flag.CommandLine)
+	retention   = settings.RetentionFlags(flag.CommandLine)
+	interval    = flag.Duration("interval", 5*time.Minute, "time between retrieval runs")
+	timeout     = flag.Duration("timeout", elecciones.DefaultFetcherOptions.Timeout, "timeout of every request to the ministry server")
+	retries     = flag.Int("retries", elecciones.DefaultFetcherOptions.MaxRetries, "number of retries of a failed request")
+	retryAfter  = flag.Duration("maxretryafter", elecciones.DefaultFetcherOptions.MaxRetryAfter, "longest Retry-After wait honoured (0 for no limit)")
+	concurrency = flag.Int("concurrency", elecciones.DefaultRetrieveOptions.Concurrency, "number of nodes retrieved at the same time")
+	rps         = flag.Float64("rps", elecciones.DefaultRetrieveOptions.RequestsPerSecond, "maximum requests per second to the ministry server (0 for no limit)")
+	deadline    = flag.Duration("deadline", elecciones.DefaultRetrieveOptions.Deadline, "maximum duration of a retrieval run (0 for no limit); 90% of the time between runs if not given")
+	adaptive    = flag.Bool("adaptive", false, "poll every level at its own interval and stop polling the nodes whose count is complete")
 )
 
-//TODO: Add flags to configure filename and refresh time
 func main() {
-	db, err := bolt.Open("congreso20D2015.db", 0600, nil)
+	if err := settings.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	walk, err := territory.WalkOptions()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
-	http.Handle("/stats", elecciones.NewStatsHandler(db))
-	http.Handle("/dbbackup", elecciones.NewBackupHandler(db))
-	http.Handle("/testread", elecciones.NewReadHandler(db))
+	retentionPolicy, err := retention.Policy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := elecciones.OpenBoltStore(*filename, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
 
-	go http.ListenAndServe(":8080", nil)
+	opts := elecciones.DefaultFetcherOptions
+	opts.Timeout = *timeout
+	opts.MaxRetries = *retries
+	opts.MaxRetryAfter = *retryAfter
+	fetcher := elecciones.NewFetcher(opts)
 
-	conf, err := elecciones.LoadConfig()
+	conf, err := territory.LoadConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	go elecciones.RetrieveData(conf, db)
+	retrieveOpts := elecciones.RetrieveOptions{
+		Concurrency:       *concurrency,
+		RequestsPerSecond: *rps,
+		Deadline:          *deadline,
+		Walk:              walk,
+		Retention:         retentionPolicy,
+	}
+
+	broker := elecciones.NewBroker()
+	retrieveOpts.Events = broker
+
+	every := *interval
+	reporters := []elecciones.StatsReporter{fetcher, broker}
+	if *adaptive {
+		policy := elecciones.NewPollingPolicy(elecciones.DefaultPollIntervals)
+		if err := policy.Seed(conf, store); err != nil {
+			log.Fatal(err)
+		}
+		retrieveOpts.Policy = policy
+		if policy.MinInterval() < every {
+			every = policy.MinInterval()
+		}
+		reporters = append(reporters, policy)
+	}
+
+	// Unless -deadline is given, finish every run before the next one is due
+	// so that the scheduler does not skip it.
+	if !settings.IsSet(flag.CommandLine, "deadline") {
+		retrieveOpts.Deadline = every - every/10
+	}
+
+	scheduler := elecciones.NewScheduler(every, func(ctx context.Context) {
+		elecciones.RetrieveData(ctx, conf, store, fetcher, retrieveOpts)
+	})
+	reporters = append(reporters, scheduler)
+
+	http.Handle("/stats", elecciones.NewStatsHandler(store, reporters...))
+	http.Handle("/dbbackup", elecciones.NewBackupHandler(store))
+	http.Handle("/results/", http.StripPrefix("/results/", elecciones.NewReadHandler(store)))
+	http.Handle("/history/", http.StripPrefix("/history/", elecciones.NewHistoryHandler(store)))
+	http.Handle("/updates/", http.StripPrefix("/updates/", elecciones.NewUpdatesHandler(store)))
+	http.Handle("/quarantine", elecciones.NewQuarantineHandler(store))
+	http.Handle("/events", elecciones.NewEventsHandler(broker))
+	http.Handle("/at", elecciones.NewStateHandler(conf, store))
+
+	server := &http.Server{Addr: *port}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	ticker := time.NewTicker(5 * time.Minute)
+	scheduler.Run(ctx)
+	log.Println("Shutting down")
+	broker.Close()
 
-	for _ = range ticker.C {
-		go elecciones.RetrieveData(conf, db)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println(err)
 	}
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/mgilbir/elecciones"
+	"github.com/mgilbir/elecciones/cmd/internal/settings"
+	"github.com/mgilbir/elecciones/seats"
+)
+
+var (
+	filename   = settings.DB(flag.CommandLine)
+	magnitudes = flag.String("magnitudes", "data/CONGRESO.json", "file with the seats per circumscription")
+	year       = flag.String("year", "2015", "year of the seats per circumscription")
+	method     = flag.String("method", seats.DHondt.Name(), "apportionment method: dhondt, sainte-lague, modified-sainte-lague, hare or droop")
+	threshold  = flag.Float64("threshold", seats.Threshold, "minimum share of the valid votes of a circumscription to get seats")
+	at         = flag.String("at", "", "use the snapshot stored at or before this RFC3339 time instead of the latest one")
+	detail     = flag.Bool("detail", false, "report the seat deltas of every circumscription")
+)
+
+func main() {
+	if err := settings.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	m, err := seats.ParseMethod(*method)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mag, err := seats.LoadMagnitudesFile(*magnitudes, *year)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	until := time.Now()
+	if *at != "" {
+		until, err = time.Parse(time.RFC3339, *at)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	store, err := elecciones.OpenBoltStore(*filename, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	state, err := elecciones.StateOf(store, mag.Circumscriptions(), until)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	results := state.Results()
+	official := make(map[string]map[string]int)
+
+	for path, s := range state {
+		official[path] = make(map[string]int)
+		for _, p := range s.Response.Results.Parties {
+			if p.Seats > 0 {
+				official[path][p.Acronym] = p.Seats
+			}
+		}
+	}
+
+	projection := seats.ProjectWith(mag, results, m, *threshold)
+	for _, path := range projection.Missing {
+		log.Printf("No results for circumscription %s\n", path)
+	}
+
+	nationalOfficial := make(map[string]int)
+	for _, allocation := range official {
+		for party, s := range allocation {
+			nationalOfficial[party] += s
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Method: %s, threshold: %g\n\n", m.Name(), *threshold)
+	report(w, "ES", nationalOfficial, projection.National)
+
+	if *detail {
+		for _, path := range mag.Circumscriptions() {
+			if _, ok := projection.Circumscriptions[path]; !ok {
+				continue
+			}
+			fmt.Fprintln(w)
+			report(w, path, official[path], projection.Circumscriptions[path])
+		}
+	}
+}
+
+func report(w *tabwriter.Writer, territory string, official, simulated map[string]int) {
+	parties := make(map[string]struct{})
+	for p := range official {
+		parties[p] = struct{}{}
+	}
+	for p := range simulated {
+		parties[p] = struct{}{}
+	}
+
+	var order []string
+	for p := range parties {
+		order = append(order, p)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if simulated[a] != simulated[b] {
+			return simulated[a] > simulated[b]
+		}
+		if official[a] != official[b] {
+			return official[a] > official[b]
+		}
+		return a < b
+	})
+
+	fmt.Fprintf(w, "%s\tOfficial\tSimulated\tDelta\n", territory)
+	for _, p := range order {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%+d\n", p, official[p], simulated[p], simulated[p]-official[p])
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/mgilbir/elecciones"
+	"github.com/mgilbir/elecciones/cmd/internal/settings"
+)
+
+var (
+	format    = flag.String("format", "json", "output format: json for the nested tree, csv for one row per territory")
+	output    = flag.String("out", "", "output filename (standard output by default)")
+	territory = settings.TerritoryFlags(flag.CommandLine)
+)
+
+func main() {
+	if err := settings.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	var write func(io.Writer, *elecciones.Config, elecciones.WalkOptions) error
+	switch *format {
+	case "json":
+		write = writeJSON
+	case "csv":
+		write = writeCSV
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	walk, err := territory.WalkOptions()
+	if err != nil {
+		return err
+	}
+
+	conf, err := territory.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if walk.Root != "" && conf.ByPath(walk.Root) == nil {
+		return fmt.Errorf("unknown root %s", walk.Root)
+	}
+
+	if *output == "" {
+		return write(os.Stdout, conf, walk)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := write(f, conf, walk); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeJSON writes the tree nested from -root, or from the pais nodes if it
+// is not given. -levels and -maxdepth only apply to the csv format.
+func writeJSON(w io.Writer, conf *elecciones.Config, walk elecciones.WalkOptions) error {
+	var tree interface{} = conf.Tree()
+	if walk.Root != "" {
+		n := conf.ByPath(walk.Root)
+		if n == nil {
+			return fmt.Errorf("unknown root %s", walk.Root)
+		}
+		tree = elecciones.NewTerritoryTree(n)
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(tree)
+}
+
+func writeCSV(w io.Writer, conf *elecciones.Config, walk elecciones.WalkOptions) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	if err := cw.Write(elecciones.Territory{}.GetCsvHeaders()); err != nil {
+		return err
+	}
+
+	walk.Order = elecciones.OrderDepthFirst
+	for n := range conf.WalkWith(walk) {
+		if err := cw.Write(elecciones.NewTerritory(n).ExportToCsv()); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,133 @@
+package elecciones
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+)
+
+// compactBatch is the number of snapshots Compact writes at a time.
+const compactBatch = 1000
+
+// CompactStats summarises the result of Compact.
+type CompactStats struct {
+	Paths   int
+	Kept    int
+	Dropped int
+}
+
+// Compact copies the snapshots in src into dst, dropping every snapshot that
+// is identical to the previous one of the same node, and writing the rest in
+// batches. When dst is a BoltStore, the snapshots stored uncompressed in src
+// are compressed. The last time every node was seen, its cache validators,
+// its index by source update and the quarantined payloads are copied too. The
+// snapshots missing from the index, such as those stored before it was
+// introduced, are indexed, and the valid snapshots are stored with their
+// decoded Response.
+func Compact(src, dst Store) (CompactStats, error) {
+	var stats CompactStats
+
+	paths, err := src.Paths()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, path := range paths {
+		stats.Paths++
+
+		updates, err := src.Updates(path)
+		if err != nil && err != ErrUnknownPath {
+			return stats, err
+		}
+		if err := dst.Index(path, updates...); err != nil {
+			return stats, err
+		}
+
+		var (
+			last     time.Time
+			previous []byte
+			batch    []RawSnapshot
+			index    []IndexEntry
+		)
+		flush := func() error {
+			stored, err := dst.PutAll(path, batch)
+			if err != nil {
+				return err
+			}
+			stats.Kept += stored
+			stats.Dropped += len(batch) - stored
+
+			if err := dst.Index(path, index...); err != nil {
+				return err
+			}
+			batch, index = batch[:0], index[:0]
+			return nil
+		}
+
+		err = src.Range(path, time.Time{}, time.Time{}, func(t time.Time, data []byte) error {
+			last = t
+
+			if previous != nil && bytes.Equal(previous, data) {
+				stats.Dropped++
+				return nil
+			}
+			// data may only be valid until fn returns.
+			previous = append([]byte(nil), data...)
+			snapshot := RawSnapshot{Time: t, Data: previous}
+
+			if resp, err := validateResponse(http.StatusOK, data); err == nil {
+				snapshot.Response = &resp
+				if e, ok := newIndexEntry(resp, t); ok {
+					index = append(index, e)
+				}
+			}
+			batch = append(batch, snapshot)
+
+			if len(batch) < compactBatch {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return stats, err
+		}
+		if err := flush(); err != nil {
+			return stats, err
+		}
+
+		seen, err := src.LastSeen(path)
+		if err == ErrUnknownPath || (err == nil && seen.Before(last)) {
+			seen, err = last, nil
+		}
+		if err != nil {
+			return stats, err
+		}
+		if !seen.IsZero() {
+			if err := dst.Touch(path, seen); err != nil {
+				return stats, err
+			}
+		}
+
+		v, err := src.Validators(path)
+		if err != nil {
+			return stats, err
+		}
+		if v != (Validators{}) {
+			if err := dst.SetValidators(path, v); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	entries, err := src.Quarantined()
+	if err != nil {
+		return stats, err
+	}
+	for _, e := range entries {
+		if err := dst.Quarantine(e); err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
@@ -6,6 +6,8 @@ import (
 )
 
 type Config struct {
+	election Election
+
 	paises      map[string]*Node
 	comunidades map[string]*Node
 	provincias  map[string]*Node
@@ -14,6 +16,11 @@ type Config struct {
 	distritos   map[string]*Node
 }
 
+// Election returns the election the configuration was loaded for.
+func (c Config) Election() Election {
+	return c.election
+}
+
 func (c *Config) AddPais(n *Node) {
 	c.paises[n.data.ID()] = n
 }
@@ -51,7 +58,15 @@ func (c *Config) AddDistrito(n *Node) {
 	c.municipios[n.Parent().data.ID()].AddChild(n)
 }
 
+// Walk streams every node of the tree in breadth-first order, siblings
+// sorted by ID.
 func (c Config) Walk() chan *Node {
+	return c.WalkWith(WalkOptions{})
+}
+
+// walkConcurrent streams every node, walking each level in its own
+// goroutine. The order is not deterministic.
+func (c Config) walkConcurrent() chan *Node {
 	ch := make(chan *Node)
 	go func(ch chan *Node) {
 		defer close(ch)
@@ -117,8 +132,9 @@ func (c Config) Walk() chan *Node {
 	return ch
 }
 
-func NewConfig() (*Config, error) {
+func NewConfig(e Election) (*Config, error) {
 	return &Config{
+		election:    e,
 		paises:      make(map[string]*Node),
 		comunidades: make(map[string]*Node),
 		provincias:  make(map[string]*Node),
@@ -0,0 +1,106 @@
+package elecciones
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Election describes where the ministry publishes the configuration and the
+// results of a single election.
+type Election struct {
+	BaseURL string    `json:"baseURL"`
+	Code    string    `json:"code"`
+	Chamber string    `json:"chamber"`
+	Date    time.Time `json:"date"`
+}
+
+var (
+	Congreso2015 = Election{
+		BaseURL: "http://resultadosgenerales2015.interior.es",
+		Code:    "ES201512-CON-ES",
+		Chamber: "congreso",
+		Date:    time.Date(2015, time.December, 20, 0, 0, 0, 0, time.UTC),
+	}
+
+	Congreso2016 = Election{
+		BaseURL: "http://resultadosgenerales2016.interior.es",
+		Code:    "ES201606-CON-ES",
+		Chamber: "congreso",
+		Date:    time.Date(2016, time.June, 26, 0, 0, 0, 0, time.UTC),
+	}
+
+	DefaultElection = Congreso2015
+)
+
+// knownElections indexes the built-in elections by their code.
+var knownElections = map[string]Election{
+	Congreso2015.Code: Congreso2015,
+	Congreso2016.Code: Congreso2016,
+}
+
+// ConfigURL returns the URL of one of the territorial configuration files
+// (pais.json, comunidad.json, ...) of the election.
+func (e Election) ConfigURL(file string) string {
+	return fmt.Sprintf("%s/%s/config/%s/%s", e.BaseURL, e.Chamber, e.Code, file)
+}
+
+// ResultsURL returns the URL of the results for the node at the given path.
+func (e Election) ResultsURL(path string) string {
+	return fmt.Sprintf("%s/%s/results/%s/%s/info.json", e.BaseURL, e.Chamber, e.Code, path)
+}
+
+func (e Election) String() string {
+	return e.Code
+}
+
+// LoadElection reads an election descriptor from a JSON file, or from a YAML
+// file if its extension is .yaml or .yml. Both use the same keys.
+func LoadElection(filename string) (Election, error) {
+	var e Election
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		err = decodeYAML(f, &e)
+	default:
+		err = json.NewDecoder(f).Decode(&e)
+	}
+	if err != nil {
+		return e, fmt.Errorf("parsing election %s: %v", filename, err)
+	}
+
+	if e.BaseURL == "" || e.Code == "" || e.Chamber == "" {
+		return e, fmt.Errorf("election %s: baseURL, code and chamber are required", filename)
+	}
+	e.BaseURL = strings.TrimSuffix(e.BaseURL, "/")
+
+	return e, nil
+}
+
+// ParseElection resolves the value of an -election flag. It accepts either
+// the code of a built-in election (e.g. ES201512-CON-ES) or the path to a
+// JSON or YAML election descriptor. An empty value selects DefaultElection.
+func ParseElection(s string) (Election, error) {
+	if s == "" {
+		return DefaultElection, nil
+	}
+
+	if e, ok := knownElections[s]; ok {
+		return e, nil
+	}
+
+	e, err := LoadElection(s)
+	if os.IsNotExist(err) {
+		return e, fmt.Errorf("unknown election %q: not a built-in election nor a descriptor file", s)
+	}
+	return e, err
+}
@@ -0,0 +1,57 @@
+package elecciones
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseElection(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	valid := write("valid.json", `{"baseURL":"http://example.com/","code":"ES201911-CON-ES","chamber":"congreso","date":"2019-11-10T00:00:00Z"}`)
+	november := Election{BaseURL: "http://example.com", Code: "ES201911-CON-ES", Chamber: "congreso", Date: time.Date(2019, time.November, 10, 0, 0, 0, 0, time.UTC)}
+	noCode := write("nocode.json", `{"baseURL":"http://example.com","chamber":"congreso"}`)
+	yaml := write("valid.yaml", "# Repeat of April\nbaseURL: http://example.com/\ncode: 'ES201911-CON-ES'\nchamber: congreso # lower house\ndate: 2019-11-10\n")
+	nested := write("nested.yml", "baseURL: http://example.com\nelection:\n  code: ES201911-CON-ES\n")
+
+	tests := []struct {
+		value string
+		want  Election
+		err   string
+	}{
+		{"", DefaultElection, ""},
+		{"ES201606-CON-ES", Congreso2016, ""},
+		{valid, november, ""},
+		{yaml, november, ""},
+		{noCode, Election{}, "required"},
+		{nested, Election{}, "not supported"},
+		{"ES209901-CON-ES", Election{}, "unknown election"},
+	}
+
+	for _, tt := range tests {
+		e, err := ParseElection(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: got error %v, want one containing %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.value, err)
+			continue
+		}
+		if e.BaseURL != tt.want.BaseURL || e.Code != tt.want.Code || e.Chamber != tt.want.Chamber || !e.Date.Equal(tt.want.Date) {
+			t.Errorf("%q: got %+v, want %+v", tt.value, e, tt.want)
+		}
+	}
+}
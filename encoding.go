@@ -0,0 +1,46 @@
+package elecciones
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+// Stored values start with a format marker. Values written before
+// compression was introduced are the raw bodies returned by the server, which
+// never start with a marker byte.
+const (
+	formatGzip byte = 0x01
+)
+
+// encodeEntry compresses data for storage.
+func encodeEntry(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte(formatGzip)
+
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// DecodeEntry returns the original body of a value stored in a node bucket,
+// whatever the format it was stored with.
+func DecodeEntry(v []byte) ([]byte, error) {
+	if len(v) == 0 || v[0] != formatGzip {
+		return v, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(v[1:]))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
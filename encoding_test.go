@@ -0,0 +1,38 @@
+package elecciones
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeEntry(t *testing.T) {
+	data := []byte(`{"progress":{"processed":57510,"total":57511}}`)
+
+	v, err := encodeEntry(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v[0] != formatGzip {
+		t.Errorf("got format marker %#x, want %#x", v[0], formatGzip)
+	}
+
+	got, err := DecodeEntry(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("DecodeEntry() = %s, want %s", got, data)
+	}
+}
+
+func TestDecodeEntryUncompressed(t *testing.T) {
+	for _, data := range [][]byte{[]byte(`{"progress":{}}`), []byte("<html></html>"), {}} {
+		got, err := DecodeEntry(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("DecodeEntry() = %s, want %s", got, data)
+		}
+	}
+}
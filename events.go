@@ -0,0 +1,141 @@
+package elecciones
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event announces that a snapshot whose content changed has been stored.
+type Event struct {
+	Path     string       `json:"path"`
+	Time     time.Time    `json:"time"`
+	Progress ProgressInfo `json:"progress"`
+	// Parties lists the parties whose votes or seats changed since the
+	// previous snapshot of the node.
+	Parties []PartyDelta `json:"parties,omitempty"`
+}
+
+// PartyDelta is the change in the results of a party between two snapshots.
+type PartyDelta struct {
+	Acronym    string `json:"acronym"`
+	Votes      int    `json:"votes"`
+	VotesDelta int    `json:"votesDelta"`
+	Seats      int    `json:"seats"`
+	SeatsDelta int    `json:"seatsDelta"`
+}
+
+// newEvent describes the change from previous to current, the snapshot of
+// path stored at t. previous is nil for the first snapshot of a node.
+func newEvent(path string, t time.Time, previous *Response, current Response) Event {
+	e := Event{Path: path, Time: t, Progress: current.Progress}
+
+	before := make(map[string]PartyResult)
+	if previous != nil {
+		for _, p := range previous.Results.Parties {
+			before[p.Acronym] = p
+		}
+	}
+
+	for _, p := range current.Results.Parties {
+		b := before[p.Acronym]
+		d := PartyDelta{
+			Acronym:    p.Acronym,
+			Votes:      p.Votes.Presential,
+			VotesDelta: p.Votes.Presential - b.Votes.Presential,
+			Seats:      p.Seats,
+			SeatsDelta: p.Seats - b.Seats,
+		}
+		if d.VotesDelta != 0 || d.SeatsDelta != 0 {
+			e.Parties = append(e.Parties, d)
+		}
+	}
+
+	return e
+}
+
+// subscriberBuffer is the number of events a subscriber may fall behind
+// before it starts missing them.
+const subscriberBuffer = 256
+
+// Broker delivers the events published by RetrieveData to its subscribers.
+// Publishing never blocks: a subscriber that falls behind misses events.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]string
+	closed      bool
+	published   int
+	dropped     int
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Event]string)}
+}
+
+// Subscribe returns a channel with the events of the nodes in the subtree of
+// the node with path root, or of every node if root is empty, and a function
+// that cancels the subscription. The channel is closed when the subscription
+// is cancelled or the broker is closed.
+func (b *Broker) Subscribe(root string) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = root
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish delivers e to the subscribers of its node.
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.published++
+	for ch, root := range b.subscribers {
+		if !inSubtree(e.Path, root) {
+			continue
+		}
+
+		select {
+		case ch <- e:
+		default:
+			b.dropped++
+		}
+	}
+}
+
+// Close closes the channels of every subscriber.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// WriteStats writes the number of subscribers and events.
+func (b *Broker) WriteStats(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fmt.Fprintf(w, "EventSubscribers: %d\n", len(b.subscribers))
+	fmt.Fprintf(w, "EventsPublished: %d\n", b.published)
+	fmt.Fprintf(w, "EventsDropped: %d\n", b.dropped)
+}
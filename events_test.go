@@ -0,0 +1,82 @@
+package elecciones
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEvent(t *testing.T) {
+	previous := Response{}
+	previous.Results.Parties = []PartyResult{
+		{Acronym: "PP", Seats: 1, Votes: Votes{Presential: 100}},
+		{Acronym: "PSOE", Seats: 1, Votes: Votes{Presential: 90}},
+	}
+
+	current := Response{Progress: ProgressInfo{Processed: 2, Total: 4}}
+	current.Results.Parties = []PartyResult{
+		{Acronym: "PP", Seats: 1, Votes: Votes{Presential: 100}},
+		{Acronym: "PSOE", Seats: 2, Votes: Votes{Presential: 150}},
+		{Acronym: "C's", Votes: Votes{Presential: 20}},
+	}
+
+	e := newEvent("ES/CA13/28", time.Now(), &previous, current)
+	if e.Progress.Processed != 2 {
+		t.Errorf("processed %d, want 2", e.Progress.Processed)
+	}
+
+	want := []PartyDelta{
+		{Acronym: "PSOE", Votes: 150, VotesDelta: 60, Seats: 2, SeatsDelta: 1},
+		{Acronym: "C's", Votes: 20, VotesDelta: 20},
+	}
+	if len(e.Parties) != len(want) {
+		t.Fatalf("got %d party deltas, want %d", len(e.Parties), len(want))
+	}
+	for i := range want {
+		if e.Parties[i] != want[i] {
+			t.Errorf("delta %d: got %+v, want %+v", i, e.Parties[i], want[i])
+		}
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	broker := NewBroker()
+	ts := httptest.NewServer(NewEventsHandler(broker))
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "?prefix=ES/CA13")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type %s, want text/event-stream", ct)
+	}
+
+	broker.Publish(Event{Path: "ES/CA01/04"})
+	broker.Publish(Event{Path: "ES/CA13/28", Progress: ProgressInfo{Processed: 1}})
+	broker.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+
+	if len(events) != 1 || events[0].Path != "ES/CA13/28" {
+		t.Errorf("got events %+v, want only ES/CA13/28", events)
+	}
+}
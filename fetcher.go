@@ -0,0 +1,231 @@
+package elecciones
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FetcherOptions configures how a Fetcher retrieves the results.
+type FetcherOptions struct {
+	// Timeout limits every single request.
+	Timeout time.Duration
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the wait between retries.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetryAfter caps the wait asked by the server with Retry-After,
+	// which may be longer than MaxBackoff. Zero means no cap.
+	MaxRetryAfter time.Duration
+}
+
+var DefaultFetcherOptions = FetcherOptions{
+	Timeout:       30 * time.Second,
+	MaxRetries:    4,
+	MinBackoff:    time.Second,
+	MaxBackoff:    time.Minute,
+	MaxRetryAfter: 10 * time.Minute,
+}
+
+// Validators are the cache validators the server returned with the last
+// body of a node, used to make conditional requests.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// FetchResult is the outcome of the last request made by Fetch.
+type FetchResult struct {
+	Status     int
+	Body       []byte
+	Attempts   int
+	Validators Validators
+}
+
+// NotModified reports whether the server answered that the body did not
+// change since the one identified by the validators passed to Fetch.
+func (r FetchResult) NotModified() bool {
+	return r.Status == http.StatusNotModified
+}
+
+// Fetcher retrieves the results of the nodes, retrying with exponential
+// backoff when the server fails or asks to slow down, and keeps count of the
+// failures of every node.
+type Fetcher struct {
+	client *http.Client
+	opts   FetcherOptions
+	sleep  func(context.Context, time.Duration) error
+
+	mu             sync.Mutex
+	failedAttempts map[string]int
+	failedFetches  map[string]int
+}
+
+func NewFetcher(opts FetcherOptions) *Fetcher {
+	return &Fetcher{
+		client:         &http.Client{Timeout: opts.Timeout},
+		opts:           opts,
+		sleep:          sleep,
+		failedAttempts: make(map[string]int),
+		failedFetches:  make(map[string]int),
+	}
+}
+
+// Fetch retrieves url on behalf of n. If v holds the validators of the body
+// retrieved last time, the request is conditional and the server may answer
+// that nothing changed (see FetchResult.NotModified). It returns an error if
+// the request could not be completed or the server kept failing after all the
+// retries; any other response, whatever its status, is returned to be
+// validated by the caller. Fetch gives up without waiting if the server asks
+// to retry after the deadline of ctx.
+func (f *Fetcher) Fetch(ctx context.Context, n *Node, url string, v Validators) (FetchResult, error) {
+	var res FetchResult
+	var err error
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return res, err
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	for attempt := 0; ; attempt++ {
+		res = FetchResult{Attempts: attempt + 1}
+
+		var resp *http.Response
+		resp, err = f.client.Do(req)
+		var wait time.Duration
+		if err == nil {
+			res.Status = resp.StatusCode
+			res.Validators = Validators{
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+			}
+			res.Body, err = readBody(resp.Body)
+			if err == nil && !retryableStatus(resp.StatusCode) {
+				return res, nil
+			}
+			if err == nil {
+				err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+			wait = retryAfter(resp.Header.Get("Retry-After"), currentTime())
+		}
+
+		f.recordFailure(f.failedAttempts, n)
+		if attempt >= f.opts.MaxRetries || ctx.Err() != nil {
+			break
+		}
+
+		if f.opts.MaxRetryAfter > 0 && wait > f.opts.MaxRetryAfter {
+			wait = f.opts.MaxRetryAfter
+		}
+		if backoff := f.backoff(attempt); wait < backoff {
+			wait = backoff
+		}
+		if deadline, ok := ctx.Deadline(); ok && currentTime().Add(wait).After(deadline) {
+			err = fmt.Errorf("%v; next retry in %v is past the deadline", err, wait)
+			break
+		}
+		if err := f.sleep(ctx, wait); err != nil {
+			break
+		}
+	}
+
+	f.recordFailure(f.failedFetches, n)
+	return res, fmt.Errorf("giving up after %d attempts: %v", res.Attempts, err)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func readBody(r io.ReadCloser) ([]byte, error) {
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// retryableStatus reports whether a request that got status is worth
+// retrying.
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter parses the value of a Retry-After header, either a number of
+// seconds or an HTTP date. It returns zero if the value is missing or invalid.
+func retryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
+// backoff returns the wait before retry number attempt+1: an exponentially
+// growing delay with jitter, so that the retries of many nodes spread out.
+func (f *Fetcher) backoff(attempt int) time.Duration {
+	d := f.opts.MinBackoff << uint(attempt)
+	if d <= 0 || d > f.opts.MaxBackoff {
+		d = f.opts.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+func (f *Fetcher) recordFailure(m map[string]int, n *Node) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m[n.Path()]++
+}
+
+// WriteStats writes the failure counters of every node that failed at least
+// once.
+func (f *Fetcher) WriteStats(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var paths []string
+	for p := range f.failedAttempts {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintf(w, "FailingNodes: %d\n", len(paths))
+	for _, p := range paths {
+		fmt.Fprintf(w, "Failures %s -> attempts: %d, fetches: %d\n", p, f.failedAttempts[p], f.failedFetches[p])
+	}
+}
@@ -0,0 +1,181 @@
+package elecciones
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetcherRetries(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "3")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"progress":{"total":1}}`))
+		}
+	}))
+	defer ts.Close()
+
+	f := NewFetcher(DefaultFetcherOptions)
+	var waits []time.Duration
+	f.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	res, err := f.Fetch(context.Background(), n, ts.URL, Validators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != http.StatusOK || res.Attempts != 3 {
+		t.Errorf("got status %d after %d attempts, want 200 after 3", res.Status, res.Attempts)
+	}
+	if len(waits) != 2 || waits[0] < 3*time.Second {
+		t.Errorf("unexpected waits %v, want the first one to honour Retry-After", waits)
+	}
+
+	var stats strings.Builder
+	f.WriteStats(&stats)
+	if !strings.Contains(stats.String(), "Failures ES -> attempts: 2, fetches: 0") {
+		t.Errorf("unexpected stats:\n%s", stats.String())
+	}
+}
+
+func TestFetcherLongRetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "300")
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	opts := DefaultFetcherOptions
+	opts.MaxRetries = 1
+	f := NewFetcher(opts)
+	var waits []time.Duration
+	f.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	f.Fetch(context.Background(), n, ts.URL, Validators{})
+	if len(waits) != 1 || waits[0] != 300*time.Second {
+		t.Errorf("got waits %v, want Retry-After honoured beyond MaxBackoff", waits)
+	}
+
+	// Waiting 5 minutes would go past the deadline, so give up right away.
+	waits = nil
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	res, err := f.Fetch(ctx, n, ts.URL, Validators{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(waits) != 0 || res.Attempts != 1 {
+		t.Errorf("waited %v after %d attempts, want to give up after the first one", waits, res.Attempts)
+	}
+}
+
+func TestFetcherGivesUp(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	opts := DefaultFetcherOptions
+	opts.MaxRetries = 2
+	f := NewFetcher(opts)
+	f.sleep = func(context.Context, time.Duration) error { return nil }
+
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	res, err := f.Fetch(context.Background(), n, ts.URL, Validators{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if res.Status != http.StatusBadGateway || res.Attempts != 3 {
+		t.Errorf("got status %d after %d attempts, want 502 after 3", res.Status, res.Attempts)
+	}
+}
+
+func TestFetcherDoesNotRetryClientErrors(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	f := NewFetcher(DefaultFetcherOptions)
+	f.sleep = func(context.Context, time.Duration) error {
+		t.Error("unexpected retry")
+		return nil
+	}
+
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	res, err := f.Fetch(context.Background(), n, ts.URL, Validators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != http.StatusNotFound {
+		t.Errorf("got status %d, want 404", res.Status)
+	}
+}
+
+func TestFetcherConditional(t *testing.T) {
+	const etag = `"v1"`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(`{"progress":{"total":1}}`))
+	}))
+	defer ts.Close()
+
+	f := NewFetcher(DefaultFetcherOptions)
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+
+	res, err := f.Fetch(context.Background(), n, ts.URL, Validators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NotModified() || res.Validators.ETag != etag {
+		t.Fatalf("got status %d and validators %+v", res.Status, res.Validators)
+	}
+
+	res, err = f.Fetch(context.Background(), n, ts.URL, res.Validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.NotModified() {
+		t.Errorf("got status %d, want 304", res.Status)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		v    string
+		want time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-1", 0},
+		{"Sun, 20 Dec 2015 21:00:30 GMT", 30 * time.Second},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := retryAfter(tt.v, now); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -1,110 +1,445 @@
 package elecciones
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
-
-	"github.com/boltdb/bolt"
 )
 
+// StatsReporter is implemented by the components that report their own
+// statistics through a StatsHandler.
+type StatsReporter interface {
+	WriteStats(w io.Writer)
+}
+
 type StatsHandler struct {
-	db *bolt.DB
+	store     Store
+	reporters []StatsReporter
 }
 
-func NewStatsHandler(db *bolt.DB) StatsHandler {
-	return StatsHandler{db: db}
+func NewStatsHandler(store Store, reporters ...StatsReporter) StatsHandler {
+	return StatsHandler{store: store, reporters: reporters}
 }
 
 func (s StatsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if s.db == nil {
+	if s.store == nil {
 		http.Error(w, "DB not available", http.StatusInternalServerError)
+		return
 	}
 
-	err := s.db.View(func(tx *bolt.Tx) error {
-		c := tx.Cursor()
+	countPerBucket, err := s.store.Stats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("BucketCount: %d\n", len(countPerBucket))))
+	for k, v := range countPerBucket {
+		w.Write([]byte(fmt.Sprintf("Bucket %s -> %d\n", k, v)))
+	}
 
-		bucketCount := 0
-		countPerBucket := make(map[string]int)
+	for _, r := range s.reporters {
+		r.WriteStats(w)
+	}
+}
 
-		for k, _ := c.First(); k != nil; k, _ = c.Next() {
-			bucketCount++
+// ReadHandler serves the last Response stored for the node path in the
+// request URL as JSON. It is meant to be mounted with http.StripPrefix, e.g.
+//
+//	http.Handle("/results/", http.StripPrefix("/results/", NewReadHandler(store)))
+//
+// The optional at query parameter (RFC3339) returns the last Response stored
+// at or before that time instead.
+type ReadHandler struct {
+	store Store
+}
 
-			b := tx.Bucket(k)
-			countPerBucket[string(k)] = b.Stats().KeyN
-		}
+func NewReadHandler(store Store) ReadHandler {
+	return ReadHandler{store: store}
+}
 
-		w.Write([]byte(fmt.Sprintf("BucketCount: %d\n", bucketCount)))
-		for k, v := range countPerBucket {
-			w.Write([]byte(fmt.Sprintf("Bucket %s -> %d\n", k, v)))
-		}
+func (r ReadHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.store == nil {
+		http.Error(w, "DB not available", http.StatusInternalServerError)
+		return
+	}
 
-		return nil
-	})
+	at, err := timeParam(req, "at")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s, err := Latest(r.store, strings.Trim(req.URL.Path, "/"), at)
+	if err == ErrUnknownPath {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	w.Header().Set("X-Retrieved-At", s.Time.Format(time.RFC3339))
+	writeJSON(w, s.Response)
 }
 
-type ReadHandler struct {
-	db *bolt.DB
+// sizedBackuper is implemented by the stores that know the size of a backup
+// before writing it, such as BoltStore.
+type sizedBackuper interface {
+	BackupSized(w io.Writer, size func(int64)) error
 }
 
-func NewReadHandler(db *bolt.DB) ReadHandler {
-	return ReadHandler{db: db}
+type BackupHandler struct {
+	store Store
 }
 
-func (r ReadHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if r.db == nil {
+func NewBackupHandler(store Store) BackupHandler {
+	return BackupHandler{store: store}
+}
+
+func (b BackupHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if b.store == nil {
 		http.Error(w, "DB not available", http.StatusInternalServerError)
+		return
 	}
 
-	err := r.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("ES/CA02/50/50297/5029710"))
-		k, v := b.Cursor().Last()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="my.db"`)
+	var err error
+	if s, ok := b.store.(sizedBackuper); ok {
+		err = s.BackupSized(w, func(size int64) {
+			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		})
+	} else {
+		err = b.store.Backup(w)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
 
-		ts := time.Time{}
-		ts.UnmarshalBinary(k)
+// HistoryHandler serves the snapshots stored for the node path in the request
+// URL as JSON. It is meant to be mounted with http.StripPrefix, e.g.
+//
+//	http.Handle("/history/", http.StripPrefix("/history/", NewHistoryHandler(store)))
+//
+// The optional from and to query parameters (RFC3339) restrict the range.
+type HistoryHandler struct {
+	store Store
+}
 
-		// fmt.Println(v)
-		//
-		// bf := bytes.NewReader(v)
-		// r, err := gzip.NewReader(bf)
-		// if err != nil {
-		// 	http.Error(w, err.Error(), http.StatusInternalServerError)
-		// 	return nil
-		// }
+func NewHistoryHandler(store Store) HistoryHandler {
+	return HistoryHandler{store: store}
+}
 
-		w.Write(v)
-		return nil
-	})
+func (h HistoryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if h.store == nil {
+		http.Error(w, "DB not available", http.StatusInternalServerError)
+		return
+	}
+
+	from, err := timeParam(req, "from")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to, err := timeParam(req, "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snapshots, err := History(h.store, strings.Trim(req.URL.Path, "/"), from, to)
+	if err == ErrUnknownPath {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	writeJSON(w, snapshots)
 }
 
-type BackupHandler struct {
-	db *bolt.DB
+// UpdatesHandler serves the index by source update of the node path in the
+// request URL as JSON, with the lag between the publication of every update
+// and its retrieval. It is meant to be mounted with http.StripPrefix, e.g.
+//
+//	http.Handle("/updates/", http.StripPrefix("/updates/", NewUpdatesHandler(store)))
+//
+// With the source query parameter (RFC3339) it serves instead the Response
+// that holds the last update published at or before that time.
+type UpdatesHandler struct {
+	store Store
 }
 
-func NewBackupHandler(db *bolt.DB) BackupHandler {
-	return BackupHandler{db: db}
+func NewUpdatesHandler(store Store) UpdatesHandler {
+	return UpdatesHandler{store: store}
 }
 
-func (b BackupHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if b.db == nil {
+func (u UpdatesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if u.store == nil {
 		http.Error(w, "DB not available", http.StatusInternalServerError)
+		return
 	}
 
-	err := b.db.View(func(tx *bolt.Tx) error {
-		w.Header().Set("Content-Type", "application/octet-stream")
-		w.Header().Set("Content-Disposition", `attachment; filename="my.db"`)
-		w.Header().Set("Content-Length", strconv.Itoa(int(tx.Size())))
-		_, err := tx.WriteTo(w)
-		return err
-	})
+	path := strings.Trim(req.URL.Path, "/")
+
+	source, err := timeParam(req, "source")
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !source.IsZero() {
+		s, e, err := SnapshotForUpdate(u.store, path, source)
+		if err == ErrUnknownPath {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("X-Retrieved-At", s.Time.Format(time.RFC3339))
+		w.Header().Set("X-Source-Time", e.Source.Format(time.RFC3339))
+		writeJSON(w, s.Response)
+		return
+	}
+
+	entries, err := u.store.Updates(path)
+	if err == ErrUnknownPath {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type update struct {
+		IndexEntry
+		LagSeconds float64 `json:"lagSeconds"`
+	}
+	out := make([]update, len(entries))
+	for i, e := range entries {
+		out[i] = update{e, e.Lag().Seconds()}
+	}
+
+	writeJSON(w, out)
+}
+
+// QuarantineHandler serves the payloads that failed validation as JSON.
+type QuarantineHandler struct {
+	store Store
+}
+
+func NewQuarantineHandler(store Store) QuarantineHandler {
+	return QuarantineHandler{store: store}
+}
+
+func (q QuarantineHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if q.store == nil {
+		http.Error(w, "DB not available", http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := q.store.Quarantined()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, entries)
+}
+
+// StateHandler serves, as JSON keyed by node path, the last snapshot stored
+// at or before the t query parameter (RFC3339) for every node. Without t it
+// serves the last snapshots stored. The optional root and levels query
+// parameters restrict the nodes as WalkOptions do, e.g.
+//
+//	/at?t=2015-12-20T23:15:00%2B01:00&root=ES/CA13&levels=provincia,municipio
+type StateHandler struct {
+	conf  *Config
+	store Store
+}
+
+func NewStateHandler(conf *Config, store Store) StateHandler {
+	return StateHandler{conf: conf, store: store}
+}
+
+func (h StateHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if h.conf == nil || h.store == nil {
+		http.Error(w, "DB not available", http.StatusInternalServerError)
+		return
+	}
+
+	at, err := timeParam(req, "t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	levels, err := ParseLevels(req.URL.Query().Get("levels"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := WalkOptions{
+		Levels: levels,
+		Root:   strings.Trim(req.URL.Query().Get("root"), "/"),
+	}
+	if opts.Root != "" && h.conf.ByPath(opts.Root) == nil {
+		http.Error(w, ErrUnknownPath.Error(), http.StatusNotFound)
+		return
+	}
+
+	state, err := StateAt(h.conf, h.store, at, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, state)
+}
+
+// eventsKeepAlive is the time between the comments sent to keep an idle
+// event stream open through proxies.
+const eventsKeepAlive = 30 * time.Second
+
+// EventsHandler streams the events published by a Broker as Server-Sent
+// Events, each one a JSON encoded Event. The optional prefix query parameter
+// restricts the stream to the subtree of a node, e.g.
+//
+//	/events?prefix=ES/CA13
+type EventsHandler struct {
+	broker *Broker
+}
+
+func NewEventsHandler(broker *Broker) EventsHandler {
+	return EventsHandler{broker: broker}
+}
+
+func (h EventsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if h.broker == nil {
+		http.Error(w, "Events not available", http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := h.broker.Subscribe(strings.Trim(req.URL.Query().Get("prefix"), "/"))
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-req.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// TerritorySearchHandler serves the territories whose name contains the q
+// query parameter, ignoring case and accents, as JSON. The optional limit
+// query parameter caps the number of results (50 by default).
+type TerritorySearchHandler struct {
+	conf *Config
+}
+
+func NewTerritorySearchHandler(conf *Config) TerritorySearchHandler {
+	return TerritorySearchHandler{conf: conf}
+}
+
+func (t TerritorySearchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if t.conf == nil {
+		http.Error(w, "Config not available", http.StatusInternalServerError)
+		return
+	}
+
+	q := req.URL.Query().Get("q")
+	if strings.TrimSpace(q) == "" {
+		http.Error(w, "missing q", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if v := req.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	out := []Territory{}
+	for _, n := range t.conf.SearchByName(q) {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, NewTerritory(n))
+	}
+
+	writeJSON(w, out)
+}
+
+// timeParam parses the RFC3339 query parameter name. It returns the zero time
+// if the parameter is not present.
+func timeParam(req *http.Request, name string) (time.Time, error) {
+	v := req.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return t, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
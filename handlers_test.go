@@ -0,0 +1,69 @@
+package elecciones
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReadHandler(t *testing.T) {
+	s := NewMemoryStore()
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	s.Put("ES/CA13/28", base, []byte(`{"progress":{"processed":1,"total":2}}`))
+	s.Put("ES/CA13/28", base.Add(5*time.Minute), []byte(`{"progress":{"processed":2,"total":2}}`))
+
+	h := http.StripPrefix("/results/", NewReadHandler(s))
+
+	tests := []struct {
+		url       string
+		status    int
+		processed int
+	}{
+		{"/results/ES/CA13/28", http.StatusOK, 2},
+		{"/results/ES/CA13/28?at=2015-12-20T21:01:00Z", http.StatusOK, 1},
+		{"/results/ES/CA13/28?at=yesterday", http.StatusBadRequest, 0},
+		{"/results/ES/CA13/99", http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.url, w.Code, tt.status)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var resp Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Progress.Processed != tt.processed {
+			t.Errorf("%s: processed %d, want %d", tt.url, resp.Progress.Processed, tt.processed)
+		}
+	}
+}
+
+func TestBackupHandlerContentLength(t *testing.T) {
+	s := openTestStore(t)
+	if _, err := s.Put("ES", time.Now(), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	NewBackupHandler(s).ServeHTTP(w, httptest.NewRequest("GET", "/dbbackup", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusOK)
+	}
+	if got, want := w.Header().Get("Content-Length"), strconv.Itoa(w.Body.Len()); got != want {
+		t.Errorf("Content-Length %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package elecciones
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+)
+
+// IndexEntry links an update of the results published by the ministry, as
+// described by the ProgressInfo of a Response, with the time we first
+// retrieved it.
+type IndexEntry struct {
+	// Source is the ProgressInfo.Timestamp of the update.
+	Source    time.Time `json:"source"`
+	Processed int       `json:"processed"`
+	Total     int       `json:"total"`
+	// Retrieved is the time of the snapshot that holds the update.
+	Retrieved time.Time `json:"retrieved"`
+}
+
+// Lag returns how long after being published the update was retrieved.
+func (e IndexEntry) Lag() time.Duration {
+	return e.Retrieved.Sub(e.Source)
+}
+
+// sourceTime converts a ProgressInfo.Timestamp, in milliseconds since the
+// epoch, to a time.
+func sourceTime(ms int64) time.Time {
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC()
+}
+
+// newIndexEntry returns the index entry of resp, retrieved at t. It reports
+// false if resp carries no source timestamp.
+func newIndexEntry(resp Response, t time.Time) (IndexEntry, bool) {
+	if resp.Progress.Timestamp == 0 {
+		return IndexEntry{}, false
+	}
+
+	return IndexEntry{
+		Source:    sourceTime(resp.Progress.Timestamp),
+		Processed: resp.Progress.Processed,
+		Total:     resp.Progress.Total,
+		Retrieved: t,
+	}, true
+}
+
+// indexSnapshot records resp, stored for path at t, in the index of s.
+func indexSnapshot(s Store, path string, resp Response, t time.Time) error {
+	e, ok := newIndexEntry(resp, t)
+	if !ok {
+		return nil
+	}
+	return s.Index(path, e)
+}
+
+// errFound stops a Range once the snapshot looked for is found.
+var errFound = errors.New("found")
+
+// SnapshotForUpdate returns the snapshot of the node at path that holds the
+// last update published by the ministry at or before source, along with its
+// index entry. If that snapshot was deleted by a RetentionPolicy, the next
+// snapshot kept is returned if it holds the same update; otherwise
+// ErrUnknownPath is returned.
+func SnapshotForUpdate(s Store, path string, source time.Time) (Snapshot, IndexEntry, error) {
+	entries, err := s.Updates(path)
+	if err != nil {
+		return Snapshot{}, IndexEntry{}, err
+	}
+
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Source.After(source) })
+	if i == 0 {
+		return Snapshot{}, IndexEntry{}, ErrUnknownPath
+	}
+	e := entries[i-1]
+
+	var snapshot Snapshot
+	err = s.Range(path, e.Retrieved, time.Time{}, func(t time.Time, data []byte) error {
+		snapshot = Snapshot{Time: t}
+		if err := json.Unmarshal(data, &snapshot.Response); err != nil {
+			log.Printf("Skipping entry of %s: %v\n", path, err)
+			return nil
+		}
+		return errFound
+	})
+	if err != errFound {
+		if err == nil {
+			err = ErrUnknownPath
+		}
+		return Snapshot{}, IndexEntry{}, err
+	}
+
+	progress := snapshot.Response.Progress
+	if progress.Timestamp == 0 || !sourceTime(progress.Timestamp).Equal(e.Source) {
+		return Snapshot{}, IndexEntry{}, ErrUnknownPath
+	}
+
+	return snapshot, e, nil
+}
@@ -0,0 +1,96 @@
+package elecciones
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSnapshotForUpdate(t *testing.T) {
+	stores := map[string]Store{
+		"bolt":   openTestStore(t),
+		"memory": NewMemoryStore(),
+	}
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	for name, s := range stores {
+		// The ministry publishes every 10 minutes and we retrieve every
+		// update 3 minutes later, twice.
+		for i := 0; i < 3; i++ {
+			source := base.Add(time.Duration(i) * 10 * time.Minute)
+			data := fmt.Sprintf(`{"progress":{"processed":%d,"total":3,"timestamp":%d}}`, i, source.UnixNano()/int64(time.Millisecond))
+
+			var resp Response
+			if err := json.Unmarshal([]byte(data), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, delay := range []time.Duration{3 * time.Minute, 8 * time.Minute} {
+				retrieved := source.Add(delay)
+				if stored, _ := s.Put("ES", retrieved, []byte(data)); !stored {
+					continue
+				}
+				if err := indexSnapshot(s, "ES", resp, retrieved); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		entries, err := s.Updates("ES")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("%s: got %d index entries, want 3", name, len(entries))
+		}
+		for _, e := range entries {
+			if e.Lag() != 3*time.Minute {
+				t.Errorf("%s: update %v retrieved with a lag of %v, want 3m", name, e.Source, e.Lag())
+			}
+		}
+
+		snapshot, e, err := SnapshotForUpdate(s, "ES", base.Add(15*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.Response.Progress.Processed != 1 || !e.Source.Equal(base.Add(10*time.Minute)) {
+			t.Errorf("%s: got processed %d from update %v, want the update of 21:10", name, snapshot.Response.Progress.Processed, e.Source)
+		}
+
+		if _, _, err := SnapshotForUpdate(s, "ES", base.Add(-time.Minute)); err != ErrUnknownPath {
+			t.Errorf("%s: got error %v before the first update, want ErrUnknownPath", name, err)
+		}
+
+		// Once its snapshot is deleted, the next one holds a later update.
+		if err := s.DeleteAll("ES", []time.Time{base.Add(13 * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := SnapshotForUpdate(s, "ES", base.Add(15*time.Minute)); err != ErrUnknownPath {
+			t.Errorf("%s: got error %v for a deleted update, want ErrUnknownPath", name, err)
+		}
+	}
+}
+
+func TestCompactIndexes(t *testing.T) {
+	src := openTestStore(t)
+	dst := NewMemoryStore()
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		source := base.Add(time.Duration(i) * 10 * time.Minute)
+		putTestEntry(t, src, "ES", source.Add(time.Minute), fmt.Sprintf(`{"progress":{"processed":%d,"total":3,"timestamp":%d}}`, i, source.UnixNano()/int64(time.Millisecond)))
+	}
+
+	if _, err := Compact(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := dst.Updates("ES")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("got %d index entries, want 3", len(entries))
+	}
+}
@@ -0,0 +1,92 @@
+package elecciones
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the level of a node in the territorial tree.
+type Level int
+
+const (
+	LevelPais Level = iota
+	LevelComunidad
+	LevelProvincia
+	LevelIsla
+	LevelMunicipio
+	LevelDistrito
+)
+
+// Levels lists every level from the top of the tree down.
+var Levels = []Level{LevelPais, LevelComunidad, LevelProvincia, LevelIsla, LevelMunicipio, LevelDistrito}
+
+var levelNames = map[Level]string{
+	LevelPais:      "pais",
+	LevelComunidad: "comunidad",
+	LevelProvincia: "provincia",
+	LevelIsla:      "isla",
+	LevelMunicipio: "municipio",
+	LevelDistrito:  "distrito",
+}
+
+func (l Level) String() string {
+	if s, ok := levelNames[l]; ok {
+		return s
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel returns the level with the given name (pais, comunidad,
+// provincia, isla, municipio or distrito).
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown level %q", s)
+}
+
+// ParseLevels parses a comma separated list of levels.
+func ParseLevels(s string) ([]Level, error) {
+	var out []Level
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		l, err := ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+func hasLevel(levels []Level, l Level) bool {
+	for _, v := range levels {
+		if v == l {
+			return true
+		}
+	}
+	return false
+}
+
+// levelOf returns the level of the raw node a Node was built from.
+func levelOf(data RawNode) Level {
+	switch data.(type) {
+	case Pais:
+		return LevelPais
+	case Comunidad:
+		return LevelComunidad
+	case Provincia:
+		return LevelProvincia
+	case Isla:
+		return LevelIsla
+	case Municipio:
+		return LevelMunicipio
+	default:
+		return LevelDistrito
+	}
+}
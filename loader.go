@@ -2,158 +2,86 @@ package elecciones
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
-	"net/http"
 )
 
-var (
-	paisURL      = "http://resultadosgenerales2015.interior.es/congreso/config/ES201512-CON-ES/pais.json"
-	comunidadURL = "http://resultadosgenerales2015.interior.es/congreso/config/ES201512-CON-ES/comunidad.json"
-	provinciaURL = "http://resultadosgenerales2015.interior.es/congreso/config/ES201512-CON-ES/provincia.json"
-	islasURL     = "http://resultadosgenerales2015.interior.es/congreso/config/ES201512-CON-ES/islas.json"
-	municipioURL = "http://resultadosgenerales2015.interior.es/congreso/config/ES201512-CON-ES/municipio.json"
-	distritoURL  = "http://resultadosgenerales2015.interior.es/congreso/config/ES201512-CON-ES/distrito.json"
-
-	urlFormat = "http://resultadosgenerales2015.interior.es/congreso/results/ES201512-CON-ES/%s/info.json"
+const (
+	paisFile      = "pais.json"
+	comunidadFile = "comunidad.json"
+	provinciaFile = "provincia.json"
+	islasFile     = "islas.json"
+	municipioFile = "municipio.json"
+	distritoFile  = "distrito.json"
 )
 
-func loadPaises() (Paises, error) {
-	var paises Paises
-	resp, err := http.Get(paisURL)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	json.Unmarshal(body, &paises)
-	return paises, nil
-}
-
-func loadComunidades() (Comunidades, error) {
-	var comunidades Comunidades
-	resp, err := http.Get(comunidadURL)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	json.Unmarshal(body, &comunidades)
-	return comunidades, nil
-}
-
-func loadProvincias() (Provincias, error) {
-	var provincias Provincias
-	resp, err := http.Get(provinciaURL)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+func loadConfigFile(src ConfigSource, name string, v interface{}) error {
+	r, err := src.Open(name)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer r.Close()
 
-	json.Unmarshal(body, &provincias)
-	return provincias, nil
-}
-
-func loadIslas() (Islas, error) {
-	var islas Islas
-	resp, err := http.Get(islasURL)
+	body, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("parsing %s: %v", name, err)
 	}
-
-	json.Unmarshal(body, &islas)
-	return islas, nil
+	return nil
 }
 
-func loadMunicipios() (Municipios, error) {
-	var municipios Municipios
-	resp, err := http.Get(municipioURL)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	json.Unmarshal(body, &municipios)
-	return municipios, nil
+// LoadConfig fetches the territorial configuration of the given election
+// and assembles it into a Config.
+func LoadConfig(e Election) (*Config, error) {
+	return LoadConfigFrom(NewHTTPConfigSource(e), e)
 }
 
-func loadDistritos() (Distritos, error) {
-	var distritos Distritos
-	resp, err := http.Get(distritoURL)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	json.Unmarshal(body, &distritos)
-	return distritos, nil
+// LoadConfigFromDir builds the Config of the given election from a directory
+// holding copies of the configuration files, such as the bundled data/.
+func LoadConfigFromDir(dir string, e Election) (*Config, error) {
+	return LoadConfigFrom(NewDirConfigSource(dir), e)
 }
 
-func LoadConfig() (*Config, error) {
-	conf, err := NewConfig()
+// LoadConfigFrom builds the Config of the given election reading the
+// configuration files from src.
+func LoadConfigFrom(src ConfigSource, e Election) (*Config, error) {
+	conf, err := NewConfig(e)
 	if err != nil {
 		return nil, err
 	}
 
-	paises, err := loadPaises()
-	if err != nil {
+	var paises Paises
+	if err := loadConfigFile(src, paisFile, &paises); err != nil {
 		return conf, err
 	}
 
-	comunidades, err := loadComunidades()
-	if err != nil {
+	var comunidades Comunidades
+	if err := loadConfigFile(src, comunidadFile, &comunidades); err != nil {
 		return conf, err
 	}
 
-	provincias, err := loadProvincias()
-	if err != nil {
+	var provincias Provincias
+	if err := loadConfigFile(src, provinciaFile, &provincias); err != nil {
 		return conf, err
 
 	}
 
-	islas, err := loadIslas()
-	if err != nil {
+	var islas Islas
+	if err := loadConfigFile(src, islasFile, &islas); err != nil {
 		return conf, err
 	}
 
-	municipios, err := loadMunicipios()
-	if err != nil {
+	var municipios Municipios
+	if err := loadConfigFile(src, municipioFile, &municipios); err != nil {
 		return conf, err
 	}
 
-	distritos, err := loadDistritos()
-	if err != nil {
+	var distritos Distritos
+	if err := loadConfigFile(src, distritoFile, &distritos); err != nil {
 		return conf, err
 	}
 
@@ -236,20 +164,3 @@ func LoadConfig() (*Config, error) {
 
 	return conf, nil
 }
-
-func loadDataUrl(url string) ([]byte, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return []byte{}, err
-	}
-	defer resp.Body.Close()
-
-	// var b bytes.Buffer
-	// w := gzip.NewWriter(&b)
-	// _, err = io.Copy(w, resp.Body)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	return ioutil.ReadAll(resp.Body)
-}
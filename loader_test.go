@@ -0,0 +1,43 @@
+package elecciones
+
+import "testing"
+
+func TestLoadConfigFromDir(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := []struct {
+		level string
+		got   int
+		want  int
+	}{
+		{"paises", len(conf.paises), 1},
+		{"comunidades", len(conf.comunidades), 19},
+		{"provincias", len(conf.provincias), 52},
+		{"islas", len(conf.islas), 11},
+		{"municipios", len(conf.municipios), 8123},
+		{"distritos", len(conf.distritos), 92},
+	}
+
+	for _, c := range counts {
+		if c.got != c.want {
+			t.Errorf("%s: got %d, want %d", c.level, c.got, c.want)
+		}
+	}
+
+	n := conf.distritos["5029710"]
+	if n == nil {
+		t.Fatal("distrito 5029710 not loaded")
+	}
+	if got, want := n.Path(), "ES/CA02/50/50297/5029710"; got != want {
+		t.Errorf("Path() = %s, want %s", got, want)
+	}
+}
+
+func TestLoadConfigFromDirMissing(t *testing.T) {
+	if _, err := LoadConfigFromDir("does-not-exist", DefaultElection); err == nil {
+		t.Error("expected an error loading from a missing directory")
+	}
+}
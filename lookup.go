@@ -0,0 +1,97 @@
+package elecciones
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ByPath returns the node with the given path, e.g. ES/CA13/28, or nil if
+// there is none.
+func (c Config) ByPath(path string) *Node {
+	path = strings.Trim(path, "/")
+	id := path[strings.LastIndex(path, "/")+1:]
+
+	for _, l := range Levels {
+		if n, ok := c.levelNodes(l)[id]; ok && n.Path() == path {
+			return n
+		}
+	}
+	return nil
+}
+
+// ByID returns the node of the given level and ID, or nil if there is none.
+func (c Config) ByID(level Level, id string) *Node {
+	return c.levelNodes(level)[id]
+}
+
+// SearchByName returns the nodes whose name contains q, ignoring case and
+// accents, sorted by level and name.
+func (c Config) SearchByName(q string) []*Node {
+	q = foldName(strings.TrimSpace(q))
+	if q == "" {
+		return nil
+	}
+
+	var out []*Node
+	for _, l := range Levels {
+		for _, n := range c.levelNodes(l) {
+			if strings.Contains(foldName(n.Name()), q) {
+				out = append(out, n)
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if li, lj := out[i].Level(), out[j].Level(); li != lj {
+			return li < lj
+		}
+		if ni, nj := foldName(out[i].Name()), foldName(out[j].Name()); ni != nj {
+			return ni < nj
+		}
+		return out[i].Path() < out[j].Path()
+	})
+
+	return out
+}
+
+func (c Config) levelNodes(l Level) map[string]*Node {
+	switch l {
+	case LevelPais:
+		return c.paises
+	case LevelComunidad:
+		return c.comunidades
+	case LevelProvincia:
+		return c.provincias
+	case LevelIsla:
+		return c.islas
+	case LevelMunicipio:
+		return c.municipios
+	case LevelDistrito:
+		return c.distritos
+	}
+	return nil
+}
+
+// foldedRunes maps the accented letters found in the names of the territories
+// to their unaccented lower case form.
+var foldedRunes = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ñ': 'n', 'ç': 'c',
+}
+
+// foldName returns s in lower case and without accents, so that "alcala"
+// matches "ALCALÁ DE HENARES".
+func foldName(s string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if f, ok := foldedRunes[r]; ok {
+			return f
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,81 @@
+package elecciones
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"ES", "ES/CA13", "ES/CA13/28", "ES/CA04/07/073", "ES/CA02/50/50297/5029710"} {
+		n := conf.ByPath(path)
+		if n == nil {
+			t.Errorf("ByPath(%q) = nil", path)
+			continue
+		}
+		if n.Path() != path {
+			t.Errorf("ByPath(%q).Path() = %s", path, n.Path())
+		}
+	}
+
+	if n := conf.ByPath("ES/CA01/28"); n != nil {
+		t.Errorf("ByPath with a wrong parent returned %s", n.Path())
+	}
+
+	if n := conf.ByID(LevelProvincia, "28"); n == nil || n.Path() != "ES/CA13/28" {
+		t.Errorf("ByID(provincia, 28) = %v", n)
+	}
+	if n := conf.ByID(LevelMunicipio, "28"); n != nil {
+		t.Errorf("ByID(municipio, 28) = %s, want nil", n.Path())
+	}
+
+	found := false
+	for _, n := range conf.SearchByName("alcala de henares") {
+		if n.Path() == "ES/CA13/28/28005" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("SearchByName(alcala de henares) did not find ALCALÁ DE HENARES")
+	}
+
+	if got := conf.SearchByName("  "); got != nil {
+		t.Errorf("SearchByName of a blank query returned %d nodes", len(got))
+	}
+}
+
+func TestNodeMetadata(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := conf.ByPath("ES/CA04/07/073/07040")
+	if n == nil {
+		t.Fatal("municipio 07040 not found")
+	}
+
+	if n.ID() != "07040" || n.Level() != LevelMunicipio {
+		t.Errorf("got ID %s and level %s", n.ID(), n.Level())
+	}
+
+	var levels []Level
+	for _, a := range n.Ancestors() {
+		levels = append(levels, a.Level())
+	}
+	want := []Level{LevelPais, LevelComunidad, LevelProvincia, LevelIsla}
+	if len(levels) != len(want) {
+		t.Fatalf("got ancestors %v, want %v", levels, want)
+	}
+	for i := range want {
+		if levels[i] != want[i] {
+			t.Errorf("got ancestors %v, want %v", levels, want)
+			break
+		}
+	}
+
+	if name := conf.ByPath("ES/CA13").Name(); name != "Madrid, Comunidad de" {
+		t.Errorf("got name %q", name)
+	}
+}
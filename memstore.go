@@ -0,0 +1,290 @@
+package elecciones
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps its data in memory. It is meant for tests
+// and short-lived analyses.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	snapshots   map[string][]memoryEntry
+	seen        map[string]time.Time
+	validators  map[string]Validators
+	updates     map[string][]IndexEntry
+	quarantined []QuarantineEntry
+}
+
+// memoryEntry is a snapshot kept by a MemoryStore.
+type memoryEntry struct {
+	Time     time.Time `json:"time"`
+	Data     []byte    `json:"data"`
+	Response *Response `json:"response,omitempty"`
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		snapshots:  make(map[string][]memoryEntry),
+		seen:       make(map[string]time.Time),
+		validators: make(map[string]Validators),
+		updates:    make(map[string][]IndexEntry),
+	}
+}
+
+func (s *MemoryStore) Put(path string, t time.Time, data []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.put(path, t, data, nil), nil
+}
+
+func (s *MemoryStore) PutAll(path string, snapshots []RawSnapshot) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := 0
+	for _, snapshot := range snapshots {
+		if s.put(path, snapshot.Time, snapshot.Data, snapshot.Response) {
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// put stores data, and resp if not nil, like PutAll. The caller must hold
+// s.mu.
+func (s *MemoryStore) put(path string, t time.Time, data []byte, resp *Response) bool {
+	s.seen[path] = t
+
+	entries := s.snapshots[path]
+	if len(entries) > 0 && bytes.Equal(entries[len(entries)-1].Data, data) {
+		return false
+	}
+
+	// Snapshots are usually put in order, but keep them sorted otherwise.
+	i := sort.Search(len(entries), func(i int) bool { return !entries[i].Time.Before(t) })
+	e := memoryEntry{Time: t, Data: append([]byte(nil), data...), Response: resp}
+	if i < len(entries) && entries[i].Time.Equal(t) {
+		entries[i] = e
+	} else {
+		entries = append(entries, memoryEntry{})
+		copy(entries[i+1:], entries[i:])
+		entries[i] = e
+	}
+	s.snapshots[path] = entries
+
+	return true
+}
+
+func (s *MemoryStore) Parsed(path string, t time.Time) (Response, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.snapshots[path] {
+		if e.Time.Equal(t) && e.Response != nil {
+			return *e.Response, nil
+		}
+	}
+	return Response{}, ErrUnknownPath
+}
+
+func (s *MemoryStore) Touch(path string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen[path] = t
+	return nil
+}
+
+func (s *MemoryStore) LastSeen(path string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.seen[path]
+	if !ok {
+		return t, ErrUnknownPath
+	}
+	return t, nil
+}
+
+func (s *MemoryStore) Latest(path string, at time.Time) (time.Time, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.snapshots[path]
+	for i := len(entries) - 1; i >= 0; i-- {
+		if at.IsZero() || !entries[i].Time.After(at) {
+			return entries[i].Time, entries[i].Data, nil
+		}
+	}
+
+	return time.Time{}, nil, ErrUnknownPath
+}
+
+func (s *MemoryStore) Range(path string, from, to time.Time, fn func(t time.Time, data []byte) error) error {
+	// fn may use the store, so iterate over a copy.
+	s.mu.RLock()
+	entries, ok := s.snapshots[path]
+	entries = append([]memoryEntry(nil), entries...)
+	s.mu.RUnlock()
+
+	if !ok {
+		return ErrUnknownPath
+	}
+
+	for _, e := range entries {
+		if e.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && e.Time.After(to) {
+			break
+		}
+		if err := fn(e.Time, e.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteAll(path string, times []time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, ok := s.snapshots[path]
+	if !ok || len(times) == 0 {
+		return nil
+	}
+
+	drop := make(map[int64]bool, len(times))
+	for _, t := range times {
+		drop[t.UnixNano()] = true
+	}
+
+	kept := make([]memoryEntry, 0, len(entries))
+	for _, e := range entries {
+		if !drop[e.Time.UnixNano()] {
+			kept = append(kept, e)
+		}
+	}
+	s.snapshots[path] = kept
+
+	return nil
+}
+
+func (s *MemoryStore) Paths() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []string
+	for path := range s.snapshots {
+		out = append(out, path)
+	}
+	sort.Strings(out)
+
+	return out, nil
+}
+
+func (s *MemoryStore) Stats() (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string]int)
+	for path, entries := range s.snapshots {
+		out[path] = len(entries)
+	}
+
+	return out, nil
+}
+
+// Backup writes the whole store to w as JSON.
+func (s *MemoryStore) Backup(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return json.NewEncoder(w).Encode(struct {
+		Snapshots   map[string][]memoryEntry `json:"snapshots"`
+		Seen        map[string]time.Time     `json:"seen"`
+		Validators  map[string]Validators    `json:"validators"`
+		Updates     map[string][]IndexEntry  `json:"updates"`
+		Quarantined []QuarantineEntry        `json:"quarantined"`
+	}{s.snapshots, s.seen, s.validators, s.updates, s.quarantined})
+}
+
+func (s *MemoryStore) Validators(path string) (Validators, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.validators[path], nil
+}
+
+func (s *MemoryStore) SetValidators(path string, v Validators) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.validators[path] = v
+	return nil
+}
+
+func (s *MemoryStore) Index(path string, entries ...IndexEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range entries {
+		s.index(path, e)
+	}
+	return nil
+}
+
+// index records e like Index. The caller must hold s.mu.
+func (s *MemoryStore) index(path string, e IndexEntry) {
+	entries := s.updates[path]
+	i := sort.Search(len(entries), func(i int) bool { return !entries[i].Source.Before(e.Source) })
+	if i < len(entries) && entries[i].Source.Equal(e.Source) {
+		return
+	}
+
+	entries = append(entries, IndexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = e
+	s.updates[path] = entries
+}
+
+func (s *MemoryStore) Updates(path string) ([]IndexEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, ok := s.updates[path]
+	if !ok {
+		return nil, ErrUnknownPath
+	}
+	return append([]IndexEntry(nil), entries...), nil
+}
+
+func (s *MemoryStore) Quarantine(e QuarantineEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quarantined = append(s.quarantined, e)
+	if n := len(s.quarantined) - MaxQuarantined; n > 0 {
+		s.quarantined = append([]QuarantineEntry(nil), s.quarantined[n:]...)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Quarantined() ([]QuarantineEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]QuarantineEntry(nil), s.quarantined...), nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,121 @@
+package elecciones
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultPollIntervals polls the top of the tree, which changes with every
+// update of the count, more often than the municipalities.
+var DefaultPollIntervals = map[Level]time.Duration{
+	LevelPais:      time.Minute,
+	LevelComunidad: 2 * time.Minute,
+	LevelProvincia: 2 * time.Minute,
+	LevelIsla:      5 * time.Minute,
+	LevelMunicipio: 10 * time.Minute,
+	LevelDistrito:  10 * time.Minute,
+}
+
+// PollingPolicy decides which nodes are due for retrieval, based on their
+// level and on the progress of the count the last time they were retrieved.
+// Nodes whose count is complete are not polled anymore.
+type PollingPolicy struct {
+	intervals map[Level]time.Duration
+
+	mu    sync.Mutex
+	state map[string]pollState
+}
+
+type pollState struct {
+	last     time.Time
+	finished bool
+}
+
+func NewPollingPolicy(intervals map[Level]time.Duration) *PollingPolicy {
+	return &PollingPolicy{
+		intervals: intervals,
+		state:     make(map[string]pollState),
+	}
+}
+
+// MinInterval returns the shortest polling interval, which is how often the
+// policy should be consulted.
+func (p *PollingPolicy) MinInterval() time.Duration {
+	var min time.Duration
+	for _, d := range p.intervals {
+		if min == 0 || d < min {
+			min = d
+		}
+	}
+	return min
+}
+
+// Due reports whether n should be retrieved at now.
+func (p *PollingPolicy) Due(n *Node, now time.Time) bool {
+	p.mu.Lock()
+	s, ok := p.state[n.Path()]
+	p.mu.Unlock()
+
+	if !ok {
+		return true
+	}
+	if s.finished {
+		return false
+	}
+	return !now.Before(s.last.Add(p.intervals[n.Level()]))
+}
+
+// Polled records that n was retrieved at now without changes.
+func (p *PollingPolicy) Polled(n *Node, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.state[n.Path()]
+	s.last = now
+	p.state[n.Path()] = s
+}
+
+// Observe records that resp was retrieved for n at now.
+func (p *PollingPolicy) Observe(n *Node, resp Response, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state[n.Path()] = pollState{
+		last:     now,
+		finished: resp.Progress.Total > 0 && resp.Progress.Processed >= resp.Progress.Total,
+	}
+}
+
+// Seed initialises the policy with the last snapshot stored in s for every
+// node in conf, so that a restarted retriever does not poll finished nodes.
+func (p *PollingPolicy) Seed(conf *Config, s Store) error {
+	for n := range conf.Walk() {
+		snapshot, err := Latest(s, n.Path(), time.Time{})
+		if err == ErrUnknownPath {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		p.Observe(n, snapshot.Response, snapshot.Time)
+	}
+	return nil
+}
+
+// WriteStats writes the number of nodes polled and finished.
+func (p *PollingPolicy) WriteStats(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	finished := 0
+	for _, s := range p.state {
+		if s.finished {
+			finished++
+		}
+	}
+
+	fmt.Fprintf(w, "PolledNodes: %d\n", len(p.state))
+	fmt.Fprintf(w, "FinishedNodes: %d\n", finished)
+}
@@ -0,0 +1,45 @@
+package elecciones
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollingPolicy(t *testing.T) {
+	pais, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	municipio, _ := NewNode(Municipio{"28079", "MADRID", "28"}, nil)
+
+	p := NewPollingPolicy(DefaultPollIntervals)
+	now := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+
+	if !p.Due(pais, now) || !p.Due(municipio, now) {
+		t.Fatal("nodes never polled should be due")
+	}
+
+	counting := Response{Progress: ProgressInfo{Processed: 10, Total: 20}}
+	p.Observe(pais, counting, now)
+	p.Observe(municipio, counting, now)
+
+	later := now.Add(2 * time.Minute)
+	if !p.Due(pais, later) {
+		t.Error("pais should be due after 2 minutes")
+	}
+	if p.Due(municipio, later) {
+		t.Error("municipio should not be due after 2 minutes")
+	}
+
+	p.Polled(pais, later)
+	if p.Due(pais, later.Add(30*time.Second)) {
+		t.Error("pais should not be due 30 seconds after being polled")
+	}
+
+	finished := Response{Progress: ProgressInfo{Processed: 20, Total: 20}}
+	p.Observe(municipio, finished, later)
+	if p.Due(municipio, later.Add(24*time.Hour)) {
+		t.Error("finished nodes should never be due")
+	}
+
+	if got := p.MinInterval(); got != time.Minute {
+		t.Errorf("MinInterval() = %v, want 1m", got)
+	}
+}
@@ -0,0 +1,70 @@
+package elecciones
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxQuarantinedBody is the number of bytes of an invalid body kept for
+// inspection.
+const maxQuarantinedBody = 512
+
+// MaxQuarantined is the number of quarantined payloads a Store keeps. The
+// oldest ones are dropped to make room for new ones.
+const MaxQuarantined = 1000
+
+// QuarantineEntry describes a payload that was not stored because it failed
+// validation.
+type QuarantineEntry struct {
+	Time   time.Time `json:"time"`
+	Path   string    `json:"path"`
+	URL    string    `json:"url"`
+	Status int       `json:"status"`
+	Error  string    `json:"error"`
+	Body   string    `json:"body,omitempty"`
+}
+
+// validateResponse checks that data, retrieved with the given HTTP status,
+// is a Response and returns it decoded.
+func validateResponse(status int, data []byte) (Response, error) {
+	var resp Response
+
+	if status != http.StatusOK {
+		return resp, fmt.Errorf("unexpected status %d", status)
+	}
+
+	if len(data) == 0 {
+		return resp, errors.New("empty body")
+	}
+
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	if resp.Progress.Total == 0 {
+		return resp, errors.New("missing progress information")
+	}
+
+	return resp, nil
+}
+
+// quarantine records in s why the payload retrieved for n could not be
+// stored.
+func quarantine(s Store, n *Node, url string, status int, data []byte, reason error) error {
+	entry := QuarantineEntry{
+		Time:   currentTime(),
+		Path:   n.Path(),
+		URL:    url,
+		Status: status,
+		Error:  reason.Error(),
+	}
+	if len(data) > maxQuarantinedBody {
+		data = data[:maxQuarantinedBody]
+	}
+	entry.Body = string(data)
+
+	return s.Quarantine(entry)
+}
@@ -0,0 +1,126 @@
+package elecciones
+
+import (
+	"bytes"
+	"time"
+)
+
+// RetentionPolicy decides which snapshots of the history of a node are kept.
+// Exact duplicates of the previous snapshot are always dropped. The nodes of
+// the KeepAll levels keep every other snapshot. The history of the rest of
+// the nodes is downsampled to one snapshot per Interval once their count is
+// final, always keeping the first and the last snapshot.
+type RetentionPolicy struct {
+	KeepAll  []Level
+	Interval time.Duration
+}
+
+var DefaultRetentionPolicy = RetentionPolicy{
+	KeepAll:  []Level{LevelPais, LevelComunidad, LevelProvincia},
+	Interval: 30 * time.Minute,
+}
+
+// RetentionStats summarises the result of applying a RetentionPolicy.
+type RetentionStats struct {
+	Paths   int
+	Kept    int
+	Deleted int
+}
+
+// Apply deletes from s the snapshots of the nodes of conf that p does not
+// keep. The snapshots of paths that are not in conf are left untouched.
+func (p RetentionPolicy) Apply(conf *Config, s Store) (RetentionStats, error) {
+	paths, err := s.Paths()
+	if err != nil {
+		return RetentionStats{}, err
+	}
+	return p.ApplyTo(conf, s, paths)
+}
+
+// ApplyTo is like Apply, but only evaluates the history of the given paths.
+func (p RetentionPolicy) ApplyTo(conf *Config, s Store, paths []string) (RetentionStats, error) {
+	var stats RetentionStats
+
+	for _, path := range paths {
+		n := conf.ByPath(path)
+		if n == nil {
+			continue
+		}
+		stats.Paths++
+
+		drop, kept, err := p.expired(s, n)
+		if err != nil {
+			return stats, err
+		}
+
+		// Delete once the history has been read, as some stores do not allow
+		// writing while reading.
+		if err := s.DeleteAll(path, drop); err != nil {
+			return stats, err
+		}
+
+		stats.Kept += kept
+		stats.Deleted += len(drop)
+	}
+
+	return stats, nil
+}
+
+// downsamples reports whether storing resp as a new snapshot of n may let p
+// drop snapshots of n, that is, whether n is downsampled and its count is
+// final. Since stores never keep two identical snapshots in a row, the
+// history of the other nodes does not need to be evaluated again.
+func (p RetentionPolicy) downsamples(n *Node, resp Response) bool {
+	return p.Interval > 0 && !hasLevel(p.KeepAll, n.Level()) && countFinished(resp.Progress)
+}
+
+func countFinished(progress ProgressInfo) bool {
+	return progress.Total > 0 && progress.Processed >= progress.Total
+}
+
+// expired returns the times of the snapshots of n that p does not keep and
+// the number of snapshots it keeps.
+func (p RetentionPolicy) expired(s Store, n *Node) ([]time.Time, int, error) {
+	downsample := p.Interval > 0 && !hasLevel(p.KeepAll, n.Level())
+	if downsample {
+		last, err := Latest(s, n.Path(), time.Time{})
+		if err != nil && err != ErrUnknownPath {
+			return nil, 0, err
+		}
+		downsample = countFinished(last.Response.Progress)
+	}
+
+	var previous []byte
+	var history, drop []time.Time
+	err := s.Range(n.Path(), time.Time{}, time.Time{}, func(t time.Time, data []byte) error {
+		if previous != nil && bytes.Equal(previous, data) {
+			drop = append(drop, t)
+			return nil
+		}
+		// data may only be valid until fn returns.
+		previous = append(previous[:0], data...)
+
+		history = append(history, t)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !downsample {
+		return drop, len(history), nil
+	}
+
+	kept := 0
+	var lastKept time.Time
+	for i, t := range history {
+		if i == 0 || i == len(history)-1 || t.Sub(lastKept) >= p.Interval {
+			lastKept = t
+			kept++
+			continue
+		}
+		drop = append(drop, t)
+	}
+
+	return drop, kept, nil
+}
@@ -0,0 +1,93 @@
+package elecciones
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"bolt":   func(t *testing.T) Store { return openTestStore(t) },
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			testRetentionPolicy(t, conf, open(t))
+		})
+	}
+}
+
+func testRetentionPolicy(t *testing.T, conf *Config, s Store) {
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+
+	put := func(path string, ts time.Time, body string) {
+		if _, err := s.Put(path, ts, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every 5 minutes for two hours, finishing the count after one hour.
+	for _, path := range []string{"ES/CA13/28", "ES/CA13/28/28079", "ES/CA12/15/15030"} {
+		for i := 0; i <= 24; i++ {
+			processed := i
+			if processed > 12 {
+				processed = 12
+			}
+			body := fmt.Sprintf(`{"progress":{"processed":%d,"total":12},"seq":%d}`, processed, i)
+			if path == "ES/CA12/15/15030" {
+				// Still counting.
+				body = fmt.Sprintf(`{"progress":{"processed":%d,"total":100}}`, i)
+			}
+			put(path, base.Add(time.Duration(i)*5*time.Minute), body)
+		}
+	}
+
+	// Put only skips a body identical to the last one stored, so a body
+	// stored out of order can follow an identical one.
+	put("ES/CA12/15", base, `{"progress":{"processed":1,"total":100}}`)
+	put("ES/CA12/15", base.Add(10*time.Minute), `{"progress":{"processed":2,"total":100}}`)
+	put("ES/CA12/15", base.Add(5*time.Minute), `{"progress":{"processed":1,"total":100}}`)
+
+	stats, err := DefaultRetentionPolicy.Apply(conf, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Paths != 4 {
+		t.Errorf("applied to %d paths, want 4", stats.Paths)
+	}
+
+	counts, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One per 30 minutes plus the last one.
+	want := map[string]int{"ES/CA13/28": 25, "ES/CA13/28/28079": 5, "ES/CA12/15/15030": 25, "ES/CA12/15": 2}
+	for path, n := range want {
+		if counts[path] != n {
+			t.Errorf("%s: %d snapshots kept, want %d", path, counts[path], n)
+		}
+	}
+	if stats.Deleted != 21 {
+		t.Errorf("deleted %d snapshots, want 21", stats.Deleted)
+	}
+
+	last, err := Latest(s, "ES/CA13/28/28079", time.Time{})
+	if err != nil || !last.Time.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("last snapshot %v, %v; want the final one kept", last.Time, err)
+	}
+
+	history, err := History(s, "ES/CA12/15", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || !history[0].Time.Equal(base) {
+		t.Errorf("got history %+v, want the first of the duplicates kept", history)
+	}
+}
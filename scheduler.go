@@ -0,0 +1,113 @@
+package elecciones
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Scheduler runs a job every interval, never running two at the same time:
+// a tick that happens while the previous run is still in flight is skipped.
+type Scheduler struct {
+	interval time.Duration
+	job      func(ctx context.Context)
+
+	mu           sync.Mutex
+	runs         int
+	skipped      int
+	running      bool
+	lastStart    time.Time
+	lastDuration time.Duration
+	maxDuration  time.Duration
+}
+
+func NewScheduler(interval time.Duration, job func(ctx context.Context)) *Scheduler {
+	return &Scheduler{
+		interval: interval,
+		job:      job,
+	}
+}
+
+// Run runs the job right away and then every interval until ctx is done.
+// When ctx is done, it waits for the run in flight, which sees the
+// cancellation through its own context, before returning.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	done := make(chan struct{})
+	start := func() {
+		s.started()
+		go func() {
+			s.job(ctx)
+			done <- struct{}{}
+		}()
+	}
+
+	start()
+	running := true
+	for {
+		select {
+		case <-ctx.Done():
+			if running {
+				<-done
+				s.finished()
+			}
+			return
+		case <-done:
+			running = false
+			s.finished()
+		case <-ticker.C:
+			if running {
+				s.skip()
+				continue
+			}
+			start()
+			running = true
+		}
+	}
+}
+
+func (s *Scheduler) started() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs++
+	s.running = true
+	s.lastStart = currentTime()
+}
+
+func (s *Scheduler) finished() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.lastDuration = currentTime().Sub(s.lastStart)
+	if s.lastDuration > s.maxDuration {
+		s.maxDuration = s.lastDuration
+	}
+}
+
+func (s *Scheduler) skip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skipped++
+	log.Printf("Previous run still in flight after %v. Skipping this one\n", currentTime().Sub(s.lastStart))
+}
+
+// WriteStats writes the number of runs, how many were skipped because the
+// previous one was still in flight and how long they took.
+func (s *Scheduler) WriteStats(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "Runs: %d\n", s.runs)
+	fmt.Fprintf(w, "SkippedRuns: %d\n", s.skipped)
+	fmt.Fprintf(w, "Running: %v\n", s.running)
+	if !s.lastStart.IsZero() {
+		fmt.Fprintf(w, "LastRunStart: %s\n", s.lastStart.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "LastRunDuration: %v\n", s.lastDuration)
+	fmt.Fprintf(w, "MaxRunDuration: %v\n", s.maxDuration)
+}
@@ -0,0 +1,46 @@
+package elecciones
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	var inFlight, maxInFlight, runs int32
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	s := NewScheduler(5*time.Millisecond, func(ctx context.Context) {
+		atomic.AddInt32(&runs, 1)
+		if n := atomic.AddInt32(&inFlight, 1); n > atomic.LoadInt32(&maxInFlight) {
+			atomic.StoreInt32(&maxInFlight, n)
+		}
+		defer atomic.AddInt32(&inFlight, -1)
+
+		select {
+		case <-time.After(30 * time.Millisecond):
+		case <-ctx.Done():
+		}
+	})
+	s.Run(ctx)
+
+	if maxInFlight != 1 {
+		t.Errorf("got %d runs in flight at the same time, want 1", maxInFlight)
+	}
+	if runs < 2 {
+		t.Errorf("got %d runs, want at least 2", runs)
+	}
+	if inFlight != 0 {
+		t.Errorf("Run returned with %d runs in flight", inFlight)
+	}
+
+	var stats strings.Builder
+	s.WriteStats(&stats)
+	if strings.Contains(stats.String(), "SkippedRuns: 0\n") {
+		t.Errorf("expected skipped runs, got:\n%s", stats.String())
+	}
+}
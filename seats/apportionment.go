@@ -0,0 +1,196 @@
+package seats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mgilbir/elecciones"
+)
+
+// Threshold is the minimum share of the valid votes of a circumscription a
+// party needs to take part in the allocation of the Congreso seats.
+const Threshold = 0.03
+
+// PartyVotes is the number of votes obtained by a party.
+type PartyVotes struct {
+	Acronym string
+	Votes   int
+}
+
+// Votes extracts the votes of every party in r.
+func Votes(r elecciones.Result) []PartyVotes {
+	var out []PartyVotes
+	for _, p := range r.Parties {
+		out = append(out, PartyVotes{Acronym: p.Acronym, Votes: p.Votes.Presential})
+	}
+	return out
+}
+
+// ValidVotes returns the valid votes of r: the votes to parties plus the
+// blank votes.
+func ValidVotes(r elecciones.Result) int {
+	valid := r.Blank
+	for _, p := range r.Parties {
+		valid += p.Votes.Presential
+	}
+	return valid
+}
+
+// Method allocates a number of seats among parties. The parties passed to
+// Allocate have already passed the threshold and are sorted by votes in
+// descending order. Parties without seats are not included in the result.
+type Method interface {
+	Name() string
+	Allocate(parties []PartyVotes, seats int) map[string]int
+}
+
+var (
+	// DHondt is the highest averages method with divisors 1, 2, 3, ...
+	DHondt Method = divisorMethod{name: "dhondt", divisor: func(s int) float64 { return float64(s + 1) }}
+
+	// SainteLague is the highest averages method with divisors 1, 3, 5, ...
+	SainteLague Method = divisorMethod{name: "sainte-lague", divisor: func(s int) float64 { return float64(2*s + 1) }}
+
+	// ModifiedSainteLague is SainteLague with 1.4 as its first divisor.
+	ModifiedSainteLague Method = divisorMethod{name: "modified-sainte-lague", divisor: func(s int) float64 {
+		if s == 0 {
+			return 1.4
+		}
+		return float64(2*s + 1)
+	}}
+
+	// Hare is the largest remainder method with the votes/seats quota.
+	Hare Method = quotaMethod{name: "hare", quota: func(votes, seats int) float64 {
+		return float64(votes) / float64(seats)
+	}}
+
+	// Droop is the largest remainder method with the votes/(seats+1)+1 quota.
+	Droop Method = quotaMethod{name: "droop", quota: func(votes, seats int) float64 {
+		return math.Floor(float64(votes)/float64(seats+1)) + 1
+	}}
+)
+
+// Methods lists the available apportionment methods.
+var Methods = []Method{DHondt, SainteLague, ModifiedSainteLague, Hare, Droop}
+
+// ParseMethod returns the method with the given name.
+func ParseMethod(name string) (Method, error) {
+	for _, m := range Methods {
+		if m.Name() == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown apportionment method %q", name)
+}
+
+// Apportion allocates seats among the parties that reach threshold (a fraction
+// of validVotes) with the given method.
+func Apportion(m Method, parties []PartyVotes, seats int, validVotes int, threshold float64) map[string]int {
+	eligible := eligibleParties(parties, validVotes, threshold)
+	if len(eligible) == 0 || seats <= 0 {
+		return make(map[string]int)
+	}
+	return m.Allocate(eligible, seats)
+}
+
+// eligibleParties returns the parties with votes that reach the threshold,
+// sorted by votes in descending order.
+func eligibleParties(parties []PartyVotes, validVotes int, threshold float64) []PartyVotes {
+	min := threshold * float64(validVotes)
+
+	var out []PartyVotes
+	for _, p := range parties {
+		if p.Votes > 0 && float64(p.Votes) >= min {
+			out = append(out, p)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Votes != out[j].Votes {
+			return out[i].Votes > out[j].Votes
+		}
+		return out[i].Acronym < out[j].Acronym
+	})
+
+	return out
+}
+
+// divisorMethod is a highest averages method. divisor returns the divisor
+// applied to the votes of a party that already holds s seats.
+type divisorMethod struct {
+	name    string
+	divisor func(s int) float64
+}
+
+func (m divisorMethod) Name() string {
+	return m.name
+}
+
+func (m divisorMethod) Allocate(parties []PartyVotes, seats int) map[string]int {
+	out := make(map[string]int)
+
+	for i := 0; i < seats; i++ {
+		best := 0
+		bestAverage := float64(parties[0].Votes) / m.divisor(out[parties[0].Acronym])
+		for j := 1; j < len(parties); j++ {
+			// parties is sorted by votes, so the first party wins ties.
+			average := float64(parties[j].Votes) / m.divisor(out[parties[j].Acronym])
+			if average > bestAverage {
+				best, bestAverage = j, average
+			}
+		}
+		out[parties[best].Acronym]++
+	}
+
+	return out
+}
+
+// quotaMethod is a largest remainder method. quota returns the number of
+// votes that are worth a seat.
+type quotaMethod struct {
+	name  string
+	quota func(votes, seats int) float64
+}
+
+func (m quotaMethod) Name() string {
+	return m.name
+}
+
+func (m quotaMethod) Allocate(parties []PartyVotes, seats int) map[string]int {
+	total := 0
+	for _, p := range parties {
+		total += p.Votes
+	}
+
+	q := m.quota(total, seats)
+	out := make(map[string]int)
+	remainders := make([]float64, len(parties))
+
+	allocated := 0
+	for i, p := range parties {
+		whole := math.Floor(float64(p.Votes) / q)
+		if whole > 0 {
+			out[p.Acronym] = int(whole)
+			allocated += int(whole)
+		}
+		remainders[i] = float64(p.Votes) - whole*q
+	}
+
+	// Both the Hare and the Droop quotas leave at most one seat per party
+	// to be given by remainder.
+	order := make([]int, len(parties))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+
+	for i := 0; allocated < seats && i < len(order); i++ {
+		out[parties[order[i]].Acronym]++
+		allocated++
+	}
+
+	return out
+}
@@ -0,0 +1,103 @@
+package seats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDHondt(t *testing.T) {
+	parties := []PartyVotes{
+		{"A", 100000},
+		{"B", 80000},
+		{"C", 30000},
+		{"D", 20000},
+	}
+
+	got := Apportion(DHondt, parties, 8, 230000, Threshold)
+	want := map[string]int{"A": 4, "B": 3, "C": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apportion() = %v, want %v", got, want)
+	}
+}
+
+func TestDHondtThreshold(t *testing.T) {
+	parties := []PartyVotes{
+		{"A", 1000},
+		{"B", 20},
+	}
+
+	got := Apportion(DHondt, parties, 5, 1020, Threshold)
+	want := map[string]int{"A": 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apportion() = %v, want %v", got, want)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	parties := []PartyVotes{
+		{"Yellows", 47000},
+		{"Whites", 16000},
+		{"Reds", 15800},
+		{"Greens", 12000},
+		{"Blues", 6100},
+		{"Pinks", 3100},
+	}
+
+	tests := []struct {
+		method Method
+		want   map[string]int
+	}{
+		{DHondt, map[string]int{"Yellows": 5, "Whites": 2, "Reds": 2, "Greens": 1}},
+		{SainteLague, map[string]int{"Yellows": 4, "Whites": 2, "Reds": 2, "Greens": 1, "Blues": 1}},
+		{ModifiedSainteLague, map[string]int{"Yellows": 5, "Whites": 2, "Reds": 2, "Greens": 1}},
+		{Hare, map[string]int{"Yellows": 5, "Whites": 2, "Reds": 1, "Greens": 1, "Blues": 1}},
+		{Droop, map[string]int{"Yellows": 5, "Whites": 2, "Reds": 2, "Greens": 1}},
+	}
+
+	for _, tt := range tests {
+		got := Apportion(tt.method, parties, 10, 100000, 0)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.method.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	for _, m := range Methods {
+		got, err := ParseMethod(m.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name() != m.Name() {
+			t.Errorf("ParseMethod(%q) = %s", m.Name(), got.Name())
+		}
+	}
+
+	if _, err := ParseMethod("condorcet"); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
+
+func TestLoadMagnitudesFile(t *testing.T) {
+	m, err := LoadMagnitudesFile("../data/CONGRESO.json", "2015")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m["ES/CA13/28"]; got != 36 {
+		t.Errorf("Madrid: got %d seats, want 36", got)
+	}
+
+	circumscriptions := m.Circumscriptions()
+	if len(circumscriptions) != 52 {
+		t.Errorf("got %d circumscriptions, want 52", len(circumscriptions))
+	}
+
+	total := 0
+	for _, c := range circumscriptions {
+		total += m[c]
+	}
+	if total != m["ES"] {
+		t.Errorf("circumscriptions add up to %d seats, want %d", total, m["ES"])
+	}
+}
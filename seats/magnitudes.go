@@ -0,0 +1,64 @@
+package seats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Magnitudes holds the number of seats elected in each territory, keyed by the
+// node path (e.g. ES/CA13/28).
+type Magnitudes map[string]int
+
+// LoadMagnitudes reads the seat counts of the given year from a file with the
+// layout of data/CONGRESO.json, where each year maps
+// "<election code>/<node path>" to the number of seats.
+func LoadMagnitudes(r io.Reader, year string) (Magnitudes, error) {
+	var years map[string]map[string]int
+	if err := json.NewDecoder(r).Decode(&years); err != nil {
+		return nil, err
+	}
+
+	entries, ok := years[year]
+	if !ok {
+		return nil, fmt.Errorf("no seat magnitudes for year %s", year)
+	}
+
+	m := make(Magnitudes)
+	for k, v := range entries {
+		i := strings.Index(k, "/")
+		if i < 0 {
+			return nil, fmt.Errorf("malformed magnitude key %q", k)
+		}
+		m[k[i+1:]] = v
+	}
+
+	return m, nil
+}
+
+// LoadMagnitudesFile is LoadMagnitudes reading from the named file.
+func LoadMagnitudesFile(filename string, year string) (Magnitudes, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadMagnitudes(f, year)
+}
+
+// Circumscriptions returns the sorted paths of the territories where seats are
+// allocated, that is, the provinces.
+func (m Magnitudes) Circumscriptions() []string {
+	var out []string
+	for k := range m {
+		if strings.Count(k, "/") == 2 {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,45 @@
+package seats
+
+import "github.com/mgilbir/elecciones"
+
+// Projection holds the seats each party would obtain with the votes counted
+// so far.
+type Projection struct {
+	// Circumscriptions maps the path of every projected circumscription to
+	// the seats per party acronym.
+	Circumscriptions map[string]map[string]int
+	// National adds up the seats per party acronym over all circumscriptions.
+	National map[string]int
+	// Missing lists the circumscriptions without results.
+	Missing []string
+}
+
+// Project allocates the seats of every circumscription in m with D'Hondt and
+// the legal threshold, using the results keyed by node path.
+func Project(m Magnitudes, results map[string]elecciones.Result) Projection {
+	return ProjectWith(m, results, DHondt, Threshold)
+}
+
+// ProjectWith is Project with the given apportionment method and threshold.
+func ProjectWith(m Magnitudes, results map[string]elecciones.Result, method Method, threshold float64) Projection {
+	p := Projection{
+		Circumscriptions: make(map[string]map[string]int),
+		National:         make(map[string]int),
+	}
+
+	for _, path := range m.Circumscriptions() {
+		r, ok := results[path]
+		if !ok {
+			p.Missing = append(p.Missing, path)
+			continue
+		}
+
+		allocation := Apportion(method, Votes(r), m[path], ValidVotes(r), threshold)
+		p.Circumscriptions[path] = allocation
+		for party, seats := range allocation {
+			p.National[party] += seats
+		}
+	}
+
+	return p
+}
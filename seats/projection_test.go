@@ -0,0 +1,65 @@
+package seats
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mgilbir/elecciones"
+)
+
+func result(blank int, votes map[string]int) elecciones.Result {
+	r := elecciones.Result{Blank: blank}
+	for acronym, v := range votes {
+		r.Parties = append(r.Parties, elecciones.PartyResult{Acronym: acronym, Votes: elecciones.Votes{Presential: v}})
+	}
+	return r
+}
+
+func TestProject(t *testing.T) {
+	m := Magnitudes{
+		"ES":         10,
+		"ES/CA13":    4,
+		"ES/CA13/28": 4,
+		"ES/CA12/15": 2,
+		"ES/CA01/04": 6,
+	}
+
+	// The blank votes leave C below the threshold in Madrid: 29 of 999
+	// valid votes.
+	madrid := result(80, map[string]int{"A": 600, "B": 290, "C": 29})
+	if v := ValidVotes(madrid); v != 999 {
+		t.Errorf("ValidVotes() = %d, want 999", v)
+	}
+
+	results := map[string]elecciones.Result{
+		"ES/CA13/28": madrid,
+		"ES/CA12/15": result(0, map[string]int{"A": 100, "B": 400}),
+	}
+
+	p := Project(m, results)
+
+	wantCircumscriptions := map[string]map[string]int{
+		"ES/CA13/28": {"A": 3, "B": 1},
+		"ES/CA12/15": {"B": 2},
+	}
+	if !reflect.DeepEqual(p.Circumscriptions, wantCircumscriptions) {
+		t.Errorf("Circumscriptions = %v, want %v", p.Circumscriptions, wantCircumscriptions)
+	}
+	if want := map[string]int{"A": 3, "B": 3}; !reflect.DeepEqual(p.National, want) {
+		t.Errorf("National = %v, want %v", p.National, want)
+	}
+	if want := []string{"ES/CA01/04"}; !reflect.DeepEqual(p.Missing, want) {
+		t.Errorf("Missing = %v, want %v", p.Missing, want)
+	}
+}
+
+func TestThresholdCountsBlankVotes(t *testing.T) {
+	// 29 votes pass 3% of the 919 votes to parties, but not of the 999
+	// valid votes.
+	r := result(80, map[string]int{"A": 600, "B": 290, "C": 29})
+
+	got := Apportion(SainteLague, Votes(r), 40, ValidVotes(r), Threshold)
+	if _, ok := got["C"]; ok {
+		t.Errorf("Apportion() = %v, want C below the threshold", got)
+	}
+}
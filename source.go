@@ -0,0 +1,53 @@
+package elecciones
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ConfigSource provides the territorial configuration files of an election
+// (pais.json, comunidad.json, provincia.json, islas.json, municipio.json and
+// distrito.json).
+type ConfigSource interface {
+	Open(name string) (io.ReadCloser, error)
+}
+
+// HTTPConfigSource fetches the configuration files from the ministry server.
+type HTTPConfigSource struct {
+	election Election
+}
+
+func NewHTTPConfigSource(e Election) HTTPConfigSource {
+	return HTTPConfigSource{election: e}
+}
+
+func (s HTTPConfigSource) Open(name string) (io.ReadCloser, error) {
+	url := s.election.ConfigURL(name)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// DirConfigSource reads the configuration files from a local directory.
+type DirConfigSource struct {
+	dir string
+}
+
+func NewDirConfigSource(dir string) DirConfigSource {
+	return DirConfigSource{dir: dir}
+}
+
+func (s DirConfigSource) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, name))
+}
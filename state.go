@@ -0,0 +1,43 @@
+package elecciones
+
+import "time"
+
+// State holds the last snapshot of a set of nodes at a given time, keyed by
+// node path.
+type State map[string]Snapshot
+
+// StateAt returns the last snapshot stored in s at or before at for every
+// node of conf selected by opts. A zero at selects the last snapshot stored.
+// Nodes without snapshots by then are left out.
+func StateAt(conf *Config, s Store, at time.Time, opts WalkOptions) (State, error) {
+	var paths []string
+	for n := range conf.WalkWith(opts) {
+		paths = append(paths, n.Path())
+	}
+	return StateOf(s, paths, at)
+}
+
+// StateOf is StateAt for the nodes with the given paths.
+func StateOf(s Store, paths []string, at time.Time) (State, error) {
+	out := make(State)
+	for _, path := range paths {
+		snapshot, err := Latest(s, path, at)
+		if err == ErrUnknownPath {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[path] = snapshot
+	}
+	return out, nil
+}
+
+// Results returns the results of every node in the state, keyed by path.
+func (st State) Results() map[string]Result {
+	out := make(map[string]Result)
+	for path, s := range st {
+		out[path] = s.Response.Results.Result
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package elecciones
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStateAt(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewMemoryStore()
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	for i, path := range []string{"ES", "ES/CA13", "ES/CA13/28"} {
+		for j := 0; j < 3; j++ {
+			ts := base.Add(time.Duration(j)*10*time.Minute + time.Duration(i)*time.Minute)
+			s.Put(path, ts, []byte(fmt.Sprintf(`{"progress":{"processed":%d,"total":3}}`, j)))
+		}
+	}
+
+	state, err := StateAt(conf, s, base.Add(11*time.Minute), WalkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// At 21:11, ES and ES/CA13 have their second snapshot and ES/CA13/28
+	// only its first one.
+	want := map[string]int{"ES": 1, "ES/CA13": 1, "ES/CA13/28": 0}
+	if len(state) != len(want) {
+		t.Errorf("got %d nodes, want %d", len(state), len(want))
+	}
+	for path, processed := range want {
+		if got := state[path].Response.Progress.Processed; got != processed {
+			t.Errorf("%s: processed %d, want %d", path, got, processed)
+		}
+	}
+
+	state, err = StateAt(conf, s, base.Add(11*time.Minute), WalkOptions{Root: "ES/CA13", Levels: []Level{LevelProvincia}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state["ES/CA13/28"]; !ok || len(state) != 1 {
+		t.Errorf("got %d nodes, want only ES/CA13/28", len(state))
+	}
+}
@@ -1,48 +1,211 @@
 package elecciones
 
 import (
-	"fmt"
+	"context"
 	"log"
-	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/boltdb/bolt"
 )
 
-func storeEntry(db *bolt.DB, n *Node, data []byte) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists([]byte(n.Path()))
-		if err != nil {
-			return fmt.Errorf("create bucket: %s", err)
-		}
+// RetrieveOptions configures a run of RetrieveData.
+type RetrieveOptions struct {
+	// Concurrency is the number of nodes retrieved at the same time.
+	Concurrency int
+	// RequestsPerSecond limits the rate of requests. Zero means no limit.
+	RequestsPerSecond float64
+	// Deadline limits the duration of the whole run. The nodes not retrieved
+	// by then are skipped. Zero means no deadline.
+	Deadline time.Duration
+	// Walk selects the nodes retrieved. The zero value selects all of them.
+	Walk WalkOptions
+	// Policy, if not nil, selects the nodes retrieved in every run. Otherwise
+	// every node is retrieved.
+	Policy *PollingPolicy
+	// Events, if not nil, receives an Event for every snapshot stored.
+	Events *Broker
+	// Retention, if not nil, is applied at the end of the run to the nodes
+	// that stored a snapshot which may let it drop others.
+	Retention *RetentionPolicy
+}
 
-		now := currentTime()
-		key, err := now.MarshalBinary()
-		if err != nil {
-			return err
-		}
-		b.Put(key, data)
-		return nil
-	})
+var DefaultRetrieveOptions = RetrieveOptions{
+	Concurrency:       16,
+	RequestsPerSecond: 50,
+	Deadline:          4*time.Minute + 30*time.Second,
 }
 
-func RetrieveData(conf *Config, db *bolt.DB) {
+// RetrieveData fetches the results of every node in conf with f and stores
+// the valid ones in s.
+func RetrieveData(ctx context.Context, conf *Config, s Store, f *Fetcher, opts RetrieveOptions) {
 	log.Println("Data load initiated")
+
+	if opts.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Deadline)
+		defer cancel()
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	limiter := newRateLimiter(opts.RequestsPerSecond, concurrency)
+
+	// skipped counts the nodes not retrieved because the run was cancelled
+	// or hit its deadline, whether they were still in the walk or already
+	// handed to a worker.
+	var retrieved, failed, skipped int64
+
+	// retain collects the paths to apply opts.Retention to.
+	var (
+		mu     sync.Mutex
+		retain []string
+	)
+
+	nodes := make(chan *Node)
 	var wg sync.WaitGroup
-	for n := range conf.Walk() {
+	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
-		go func(n *Node, wg *sync.WaitGroup) {
+		go func(wg *sync.WaitGroup) {
 			defer wg.Done()
-			seconds := time.Duration(rand.Intn(200))
-			time.Sleep(seconds * time.Second)
-			data, err := loadDataUrl(n.URL())
-			if err != nil {
-				log.Println("Error retrieving URL %s: %v", n.URL(), err)
+			for n := range nodes {
+				if err := limiter.Wait(ctx); err != nil {
+					atomic.AddInt64(&skipped, 1)
+					continue
+				}
+				resp, stored, ok := retrieveNode(ctx, conf, s, f, n, opts.Events)
+				if stored && opts.Retention != nil && opts.Retention.downsamples(n, *resp) {
+					mu.Lock()
+					retain = append(retain, n.Path())
+					mu.Unlock()
+				}
+				switch {
+				case ok:
+					atomic.AddInt64(&retrieved, 1)
+				case ctx.Err() != nil:
+					atomic.AddInt64(&skipped, 1)
+				default:
+					atomic.AddInt64(&failed, 1)
+				}
+				if opts.Policy == nil || !ok {
+					continue
+				}
+				if resp != nil {
+					opts.Policy.Observe(n, *resp, currentTime())
+				} else {
+					opts.Policy.Polled(n, currentTime())
+				}
 			}
-			storeEntry(db, n, data)
-		}(n, &wg)
+		}(&wg)
 	}
+
+	now := currentTime()
+	for n := range conf.WalkWith(opts.Walk) {
+		if opts.Policy != nil && !opts.Policy.Due(n, now) {
+			continue
+		}
+
+		// Keep draining the walk after the deadline so that it finishes.
+		if ctx.Err() != nil {
+			atomic.AddInt64(&skipped, 1)
+			continue
+		}
+		select {
+		case nodes <- n:
+		case <-ctx.Done():
+			atomic.AddInt64(&skipped, 1)
+		}
+	}
+	close(nodes)
 	wg.Wait()
-	log.Println("Data load completed")
+
+	if skipped > 0 {
+		log.Printf("Data load stopped: %v. Skipped %d nodes\n", ctx.Err(), skipped)
+	}
+	log.Printf("Data load completed: %d nodes retrieved, %d failed\n", retrieved, failed)
+
+	if len(retain) > 0 {
+		stats, err := opts.Retention.ApplyTo(conf, s, retain)
+		if err != nil {
+			log.Printf("Error applying retention: %v\n", err)
+			return
+		}
+		log.Printf("Retention: deleted %d snapshots of %d nodes\n", stats.Deleted, stats.Paths)
+	}
+}
+
+// retrieveNode fetches the results of n and stores them if they are valid
+// and changed since the last time, or quarantines them if they are invalid.
+// It reports whether the results were retrieved and whether they were stored,
+// and returns them unless the server answered that they did not change. If
+// events is not nil, it publishes the snapshots stored.
+func retrieveNode(ctx context.Context, conf *Config, s Store, f *Fetcher, n *Node, events *Broker) (*Response, bool, bool) {
+	url := n.URL(conf.Election())
+
+	v, err := s.Validators(n.Path())
+	if err != nil {
+		log.Printf("Error loading validators of %s: %v\n", n.Path(), err)
+	}
+
+	res, err := f.Fetch(ctx, n, url, v)
+	if err == nil && res.NotModified() {
+		if err := s.Touch(n.Path(), currentTime()); err != nil {
+			log.Printf("Error storing %s: %v\n", n.Path(), err)
+		}
+		return nil, false, true
+	}
+
+	// A run cancelled or past its deadline is not the server's fault.
+	if err != nil && ctx.Err() != nil {
+		return nil, false, false
+	}
+	// Without a response, such as after a network error, there is no
+	// payload to quarantine.
+	if err != nil && res.Status == 0 {
+		log.Printf("Error retrieving URL %s: %v\n", url, err)
+		return nil, false, false
+	}
+
+	var resp Response
+	if err == nil {
+		resp, err = validateResponse(res.Status, res.Body)
+	}
+	if err != nil {
+		log.Printf("Error retrieving URL %s: %v\n", url, err)
+		if err := quarantine(s, n, url, res.Status, res.Body, err); err != nil {
+			log.Printf("Error quarantining %s: %v\n", n.Path(), err)
+		}
+		return nil, false, false
+	}
+
+	var previous *Response
+	if events != nil {
+		if last, err := Latest(s, n.Path(), time.Time{}); err == nil {
+			previous = &last.Response
+		}
+	}
+
+	now := currentTime()
+	count, err := s.PutAll(n.Path(), []RawSnapshot{{Time: now, Data: res.Body, Response: &resp}})
+	if err != nil {
+		log.Printf("Error storing %s: %v\n", n.Path(), err)
+		return nil, false, false
+	}
+	stored := count > 0
+
+	if stored {
+		if err := indexSnapshot(s, n.Path(), resp, now); err != nil {
+			log.Printf("Error indexing %s: %v\n", n.Path(), err)
+		}
+		if events != nil {
+			events.Publish(newEvent(n.Path(), now, previous, resp))
+		}
+	}
+
+	if err := s.SetValidators(n.Path(), res.Validators); err != nil {
+		log.Printf("Error storing validators of %s: %v\n", n.Path(), err)
+	}
+
+	return &resp, stored, true
 }
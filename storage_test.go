@@ -0,0 +1,426 @@
+package elecciones
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func openTestStore(t *testing.T) *BoltStore {
+	s, err := OpenBoltStore(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// putTestEntry stores data uncompressed, as it was stored before compression
+// was introduced.
+func putTestEntry(t *testing.T, s *BoltStore, path string, ts time.Time, data string) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(path))
+		if err != nil {
+			return err
+		}
+		k, err := ts.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return b.Put(k, []byte(data))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := openTestStore(t)
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		ts := base.Add(time.Duration(i) * 5 * time.Minute)
+		putTestEntry(t, s, "ES/CA13/28", ts, fmt.Sprintf(`{"progress":{"processed":%d,"total":4}}`, i))
+	}
+
+	all, err := History(s, "ES/CA13/28", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("got %d snapshots, want 5", len(all))
+	}
+	for i, s := range all {
+		if s.Response.Progress.Processed != i {
+			t.Errorf("snapshot %d: processed %d, want %d", i, s.Response.Progress.Processed, i)
+		}
+	}
+
+	some, err := History(s, "ES/CA13/28", base.Add(5*time.Minute), base.Add(15*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(some) != 3 {
+		t.Fatalf("got %d snapshots, want 3", len(some))
+	}
+	if !some[0].Time.Equal(base.Add(5 * time.Minute)) {
+		t.Errorf("first snapshot at %v, want %v", some[0].Time, base.Add(5*time.Minute))
+	}
+
+	if _, err := History(s, "ES/CA13/99", time.Time{}, time.Time{}); err != ErrUnknownPath {
+		t.Errorf("got error %v, want ErrUnknownPath", err)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	s := openTestStore(t)
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		ts := base.Add(time.Duration(i) * 5 * time.Minute)
+		putTestEntry(t, s, "ES/CA13", ts, fmt.Sprintf(`{"progress":{"processed":%d,"total":2}}`, i))
+	}
+
+	tests := []struct {
+		at   time.Time
+		want int
+	}{
+		{time.Time{}, 2},
+		{base, 0},
+		{base.Add(7 * time.Minute), 1},
+		{base.Add(time.Hour), 2},
+	}
+
+	for _, tt := range tests {
+		s, err := Latest(s, "ES/CA13", tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Response.Progress.Processed != tt.want {
+			t.Errorf("at %v: processed %d, want %d", tt.at, s.Response.Progress.Processed, tt.want)
+		}
+	}
+
+	if _, err := Latest(s, "ES/CA13", base.Add(-time.Minute)); err != ErrUnknownPath {
+		t.Errorf("got error %v before the first snapshot, want ErrUnknownPath", err)
+	}
+}
+
+func TestStorePutSkipsUnchanged(t *testing.T) {
+	stores := map[string]Store{
+		"bolt":   openTestStore(t),
+		"memory": NewMemoryStore(),
+	}
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	payloads := []string{`{"progress":{"processed":1}}`, `{"progress":{"processed":1}}`, `{"progress":{"processed":2}}`}
+	want := []bool{true, false, true}
+
+	for name, s := range stores {
+		for i, p := range payloads {
+			stored, err := s.Put("ES", base.Add(time.Duration(i)*time.Minute), []byte(p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored != want[i] {
+				t.Errorf("%s: payload %d: stored = %v, want %v", name, i, stored, want[i])
+			}
+		}
+
+		all, err := History(s, "ES", time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 {
+			t.Errorf("%s: got %d snapshots, want 2", name, len(all))
+		}
+
+		seen, err := s.LastSeen("ES")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := base.Add(2 * time.Minute); !seen.Equal(want) {
+			t.Errorf("%s: last seen %v, want %v", name, seen, want)
+		}
+
+		snapshot, err := Latest(s, "ES", base.Add(90*time.Second))
+		if err != nil || snapshot.Response.Progress.Processed != 1 {
+			t.Errorf("%s: Latest = %+v, %v", name, snapshot, err)
+		}
+
+		if _, err := History(s, "ES/CA13", time.Time{}, time.Time{}); err != ErrUnknownPath {
+			t.Errorf("%s: got error %v for an unknown path, want ErrUnknownPath", name, err)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	src := openTestStore(t)
+	dst := openTestStore(t)
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	for i, p := range []int{0, 0, 1, 1, 1, 2} {
+		ts := base.Add(time.Duration(i) * 5 * time.Minute)
+		putTestEntry(t, src, "ES", ts, fmt.Sprintf(`{"progress":{"processed":%d}}`, p))
+	}
+
+	stats, err := Compact(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Kept != 3 || stats.Dropped != 3 {
+		t.Errorf("kept %d and dropped %d snapshots, want 3 and 3", stats.Kept, stats.Dropped)
+	}
+
+	all, err := History(dst, "ES", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range all {
+		if s.Response.Progress.Processed != i {
+			t.Errorf("snapshot %d: processed %d, want %d", i, s.Response.Progress.Processed, i)
+		}
+	}
+
+	seen, err := dst.LastSeen("ES")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := base.Add(25 * time.Minute); !seen.Equal(want) {
+		t.Errorf("last seen %v, want %v", seen, want)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	valid, err := ioutil.ReadFile("data/test/ES_info.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status int
+		data   []byte
+		ok     bool
+	}{
+		{200, valid, true},
+		{503, valid, false},
+		{200, nil, false},
+		{200, []byte("<html>Service Unavailable</html>"), false},
+		{200, []byte("{}"), false},
+	}
+
+	for i, tt := range tests {
+		_, err := validateResponse(tt.status, tt.data)
+		if (err == nil) != tt.ok {
+			t.Errorf("case %d: got error %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	s := openTestStore(t)
+
+	n, err := NewNode(Pais{"ES", "España", "0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte("<html>Service Unavailable</html>")
+	_, reason := validateResponse(503, body)
+	if err := quarantine(s, n, "http://example.com/ES/info.json", 503, body, reason); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.Quarantined()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d quarantined entries, want 1", len(entries))
+	}
+	if e := entries[0]; e.Path != "ES" || e.Status != 503 || e.Body != string(body) {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestRetrieveNodeConditional(t *testing.T) {
+	const etag = `"v1"`
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(`{"progress":{"processed":1,"total":2}}`))
+	}))
+	defer ts.Close()
+
+	s := openTestStore(t)
+	e := Election{BaseURL: ts.URL, Code: "TEST", Chamber: "congreso"}
+	conf, err := NewConfig(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	conf.AddPais(n)
+
+	f := NewFetcher(DefaultFetcherOptions)
+	retrieveNode(context.Background(), conf, s, f, n, nil)
+	first, err := s.LastSeen("ES")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retrieveNode(context.Background(), conf, s, f, n, nil)
+	second, err := s.LastSeen("ES")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+	if !second.After(first) {
+		t.Errorf("last seen not updated by the 304 response")
+	}
+
+	all, err := History(s, "ES", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("got %d snapshots, want 1", len(all))
+	}
+}
+
+func TestRetrieveDataDeadlineDoesNotQuarantine(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer ts.Close()
+
+	s := openTestStore(t)
+	e := Election{BaseURL: ts.URL, Code: "TEST", Chamber: "congreso"}
+	conf, err := NewConfig(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	conf.AddPais(n)
+
+	opts := DefaultRetrieveOptions
+	opts.Deadline = 50 * time.Millisecond
+	RetrieveData(context.Background(), conf, s, NewFetcher(DefaultFetcherOptions), opts)
+
+	entries, err := s.Quarantined()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %d quarantined entries after the deadline, want 0: %+v", len(entries), entries)
+	}
+}
+
+func TestRetrieveNodeNetworkErrorNotQuarantined(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	s := openTestStore(t)
+	e := Election{BaseURL: ts.URL, Code: "TEST", Chamber: "congreso"}
+	conf, err := NewConfig(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := NewNode(Pais{"ES", "España", "0"}, nil)
+	conf.AddPais(n)
+
+	opts := DefaultFetcherOptions
+	opts.MaxRetries = 0
+	if _, _, ok := retrieveNode(context.Background(), conf, s, NewFetcher(opts), n, nil); ok {
+		t.Fatal("retrieved a node from a closed server")
+	}
+
+	entries, err := s.Quarantined()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %d quarantined entries after a network error, want 0: %+v", len(entries), entries)
+	}
+}
+
+func TestQuarantineIsCapped(t *testing.T) {
+	stores := map[string]Store{
+		"bolt":   openTestStore(t),
+		"memory": NewMemoryStore(),
+	}
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	for name, s := range stores {
+		for i := 0; i < MaxQuarantined+10; i++ {
+			e := QuarantineEntry{Time: base.Add(time.Duration(i) * time.Second), Path: "ES", Status: 503}
+			if err := s.Quarantine(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		entries, err := s.Quarantined()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != MaxQuarantined {
+			t.Fatalf("%s: got %d quarantined entries, want %d", name, len(entries), MaxQuarantined)
+		}
+		if !entries[0].Time.Equal(base.Add(10 * time.Second)) {
+			t.Errorf("%s: oldest entry kept from %v, want %v", name, entries[0].Time, base.Add(10*time.Second))
+		}
+	}
+}
+
+func TestStoreParsed(t *testing.T) {
+	stores := map[string]Store{
+		"bolt":   openTestStore(t),
+		"memory": NewMemoryStore(),
+	}
+
+	base := time.Date(2015, time.December, 20, 21, 0, 0, 0, time.UTC)
+	data := []byte(`{"progress":{"processed":1,"total":2},"unknown":true}`)
+	resp, err := validateResponse(http.StatusOK, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, s := range stores {
+		if _, err := s.PutAll("ES", []RawSnapshot{{Time: base, Data: data, Response: &resp}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Put("ES", base.Add(time.Minute), []byte(`{"progress":{"processed":2,"total":2}}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := s.Parsed("ES", base)
+		if err != nil || parsed.Progress != resp.Progress {
+			t.Errorf("%s: got parsed %+v, %v; want %+v", name, parsed.Progress, err, resp.Progress)
+		}
+		if _, err := s.Parsed("ES", base.Add(time.Minute)); err != ErrUnknownPath {
+			t.Errorf("%s: got error %v for a snapshot stored without Response, want ErrUnknownPath", name, err)
+		}
+
+		if err := s.DeleteAll("ES", []time.Time{base}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Parsed("ES", base); err != ErrUnknownPath {
+			t.Errorf("%s: got error %v for a deleted snapshot, want ErrUnknownPath", name, err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package elecciones
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"time"
+)
+
+// ErrUnknownPath is returned when there is no data stored for a node path.
+var ErrUnknownPath = errors.New("unknown path")
+
+// Store persists the bodies retrieved for every node, keyed by the node path
+// and the time they were retrieved, along with the bookkeeping data of the
+// retriever. The bodies passed to and returned by a Store are the ones
+// returned by the server; how they are encoded is up to the implementation.
+type Store interface {
+	// Put stores data as the snapshot of the node at path retrieved at t,
+	// unless it is identical to the last snapshot stored for path. Either
+	// way, t is recorded as the last time path was seen. It reports whether
+	// data was stored.
+	Put(path string, t time.Time, data []byte) (bool, error)
+
+	// PutAll is like calling Put for every snapshot, which must be in time
+	// order, but in a single write. The Response of the snapshots that have
+	// one is stored alongside their body. It returns the number of snapshots
+	// stored.
+	PutAll(path string, snapshots []RawSnapshot) (int, error)
+
+	// Parsed returns the Response stored alongside the snapshot of path
+	// stored at t, or ErrUnknownPath if there is none.
+	Parsed(path string, t time.Time) (Response, error)
+
+	// Touch records that the node at path was seen at t without storing a
+	// new snapshot.
+	Touch(path string, t time.Time) error
+
+	// LastSeen returns the last time the node at path was seen, whether a
+	// snapshot was stored or not.
+	LastSeen(path string) (time.Time, error)
+
+	// Latest returns the time and body of the last snapshot stored for path
+	// at or before at. A zero at returns the last snapshot stored.
+	Latest(path string, at time.Time) (time.Time, []byte, error)
+
+	// Range calls fn with the time and body of every snapshot stored for
+	// path between from and to, both inclusive, in timestamp order. A zero
+	// from or to leaves that end of the range open. Range stops and returns
+	// the error returned by fn, if any. data is only valid until fn returns.
+	Range(path string, from, to time.Time, fn func(t time.Time, data []byte) error) error
+
+	// DeleteAll removes the snapshots stored for path at times, if any, in a
+	// single write.
+	DeleteAll(path string, times []time.Time) error
+
+	// Paths returns the sorted paths of the nodes with snapshots.
+	Paths() ([]string, error)
+
+	// Stats returns the number of snapshots stored per node path.
+	Stats() (map[string]int, error)
+
+	// Backup writes a copy of the whole store to w.
+	Backup(w io.Writer) error
+
+	// Validators returns the cache validators of the last body stored for
+	// path.
+	Validators(path string) (Validators, error)
+	SetValidators(path string, v Validators) error
+
+	// Index records entries in the index of path by source update, in a
+	// single write, skipping those with the same source time as an entry
+	// already indexed.
+	Index(path string, entries ...IndexEntry) error
+	// Updates returns the index entries of path in source time order.
+	Updates(path string) ([]IndexEntry, error)
+
+	// Quarantine records a payload that failed validation, dropping the
+	// oldest ones beyond MaxQuarantined.
+	Quarantine(e QuarantineEntry) error
+	// Quarantined returns the payloads that failed validation, oldest first.
+	Quarantined() ([]QuarantineEntry, error)
+
+	Close() error
+}
+
+// RawSnapshot is the body of a snapshot as returned by the server.
+type RawSnapshot struct {
+	Time time.Time
+	Data []byte
+	// Response, if not nil, is Data decoded and validated.
+	Response *Response
+}
+
+// Snapshot is a Response as it was retrieved at a given time.
+type Snapshot struct {
+	Time     time.Time `json:"time"`
+	Response Response  `json:"response"`
+}
+
+// History returns the snapshots stored in s for the node at path between from
+// and to, both inclusive, in timestamp order. A zero from or to leaves that
+// end of the range open. Snapshots that are not a valid Response are skipped.
+func History(s Store, path string, from, to time.Time) ([]Snapshot, error) {
+	var out []Snapshot
+
+	err := s.Range(path, from, to, func(t time.Time, data []byte) error {
+		snapshot := Snapshot{Time: t}
+		if err := json.Unmarshal(data, &snapshot.Response); err != nil {
+			log.Printf("Skipping entry of %s: %v\n", path, err)
+			return nil
+		}
+
+		out = append(out, snapshot)
+		return nil
+	})
+
+	return out, err
+}
+
+// Latest returns the last snapshot stored in s for the node at path at or
+// before at. A zero at returns the last snapshot stored. The Response stored
+// alongside the snapshot is used if there is one. Snapshots that are not a
+// valid Response are skipped.
+func Latest(s Store, path string, at time.Time) (Snapshot, error) {
+	for {
+		t, data, err := s.Latest(path, at)
+		if err != nil {
+			return Snapshot{}, err
+		}
+
+		resp, err := s.Parsed(path, t)
+		if err == nil {
+			return Snapshot{Time: t, Response: resp}, nil
+		}
+		if err != ErrUnknownPath {
+			log.Printf("Error loading the parsed entry of %s: %v\n", path, err)
+		}
+
+		snapshot := Snapshot{Time: t}
+		if err := json.Unmarshal(data, &snapshot.Response); err != nil {
+			log.Printf("Skipping entry of %s: %v\n", path, err)
+			at = t.Add(-time.Nanosecond)
+			continue
+		}
+
+		return snapshot, nil
+	}
+}
@@ -0,0 +1,52 @@
+package elecciones
+
+// Territory is the serializable description of a node of the territorial
+// tree.
+type Territory struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Level    string      `json:"level"`
+	Parent   string      `json:"parent,omitempty"`
+	Path     string      `json:"path"`
+	Children []Territory `json:"children,omitempty"`
+}
+
+// NewTerritory describes n, without its children.
+func NewTerritory(n *Node) Territory {
+	t := Territory{
+		ID:    n.ID(),
+		Name:  n.Name(),
+		Level: n.Level().String(),
+		Path:  n.Path(),
+	}
+	if p := n.Parent(); p != nil {
+		t.Parent = p.ID()
+	}
+	return t
+}
+
+// NewTerritoryTree describes n and its whole subtree, siblings sorted by ID.
+func NewTerritoryTree(n *Node) Territory {
+	t := NewTerritory(n)
+	for _, c := range sortedByID(n.Children()) {
+		t.Children = append(t.Children, NewTerritoryTree(c))
+	}
+	return t
+}
+
+// Tree describes the whole territorial tree, starting at the pais nodes.
+func (c Config) Tree() []Territory {
+	var out []Territory
+	for _, n := range c.roots() {
+		out = append(out, NewTerritoryTree(n))
+	}
+	return out
+}
+
+func (t Territory) ExportToCsv() []string {
+	return []string{t.ID, t.Name, t.Level, t.Parent, t.Path}
+}
+
+func (t Territory) GetCsvHeaders() []string {
+	return []string{"id", "name", "level", "parent", "path"}
+}
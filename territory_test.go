@@ -0,0 +1,39 @@
+package elecciones
+
+import "testing"
+
+func TestTree(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree := conf.Tree()
+	if len(tree) != 1 || tree[0].Path != "ES" || tree[0].Parent != "" {
+		t.Fatalf("unexpected roots %+v", tree)
+	}
+
+	count := 0
+	var visit func(parent Territory)
+	visit = func(parent Territory) {
+		count++
+		for i, c := range parent.Children {
+			if c.Parent != parent.ID {
+				t.Errorf("%s: parent %s, want %s", c.Path, c.Parent, parent.ID)
+			}
+			if i > 0 && parent.Children[i-1].ID >= c.ID {
+				t.Errorf("%s: children not sorted by ID", parent.Path)
+			}
+			visit(c)
+		}
+	}
+	visit(tree[0])
+
+	walked := 0
+	for range conf.Walk() {
+		walked++
+	}
+	if count != walked {
+		t.Errorf("tree has %d territories, walk %d", count, walked)
+	}
+}
@@ -2,7 +2,6 @@ package elecciones
 
 import (
 	"encoding/json"
-	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -123,6 +122,36 @@ func NewNode(node RawNode, parent *Node) (*Node, error) {
 	}, nil
 }
 
+// ID returns the ministry ID of the node, e.g. 28 for the provincia of
+// Madrid.
+func (n Node) ID() string {
+	return n.data.ID()
+}
+
+// Name returns the name of the territory.
+func (n Node) Name() string {
+	return n.data.Name()
+}
+
+// Level returns the level of the node in the territorial tree.
+func (n Node) Level() Level {
+	return levelOf(n.data)
+}
+
+// Ancestors returns the ancestors of the node, from the root of the tree down
+// to its parent.
+func (n Node) Ancestors() []*Node {
+	var out []*Node
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		out = append(out, p)
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
 func (n *Node) AddChild(node *Node) {
 	n.children = append(n.children, node)
 }
@@ -146,8 +175,8 @@ func (n Node) Path() string {
 	return strings.Join(items, "/")
 }
 
-func (n Node) URL() string {
-	return fmt.Sprintf(urlFormat, n.Path())
+func (n Node) URL(e Election) string {
+	return e.ResultsURL(n.Path())
 }
 
 type ProgressInfo struct {
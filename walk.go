@@ -0,0 +1,157 @@
+package elecciones
+
+import (
+	"sort"
+	"strings"
+)
+
+// WalkOptions selects the nodes of a walk over the territorial tree.
+type WalkOptions struct {
+	// Levels restricts the walk to the nodes of these levels. Empty means
+	// every level.
+	Levels []Level
+	// Root restricts the walk to the subtree of the node with this path,
+	// e.g. ES/CA13. Empty means the whole tree.
+	Root string
+	// MaxDepth limits how many levels below Root (or below the pais nodes if
+	// Root is empty) are walked. Zero means no limit.
+	MaxDepth int
+	// Order is the order in which the nodes are walked.
+	Order WalkOrder
+}
+
+// WalkOrder is the order of a walk over the territorial tree.
+type WalkOrder int
+
+const (
+	// OrderBreadthFirst walks the tree level by level, siblings sorted by ID.
+	OrderBreadthFirst WalkOrder = iota
+	// OrderDepthFirst walks every node before its children, siblings sorted
+	// by ID.
+	OrderDepthFirst
+	// OrderConcurrent walks every level at the same time. It is the fastest
+	// walk, but the order changes on every run.
+	OrderConcurrent
+)
+
+// Match reports whether n is selected by the options.
+func (o WalkOptions) Match(n *Node) bool {
+	if len(o.Levels) > 0 && !hasLevel(o.Levels, n.Level()) {
+		return false
+	}
+
+	path := n.Path()
+	if !inSubtree(path, o.Root) {
+		return false
+	}
+
+	if o.MaxDepth > 0 {
+		depth := strings.Count(path, "/")
+		if o.Root != "" {
+			depth -= strings.Count(o.Root, "/")
+		}
+		if depth > o.MaxDepth {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inSubtree reports whether path is root or one of its descendants. Every
+// path is in the subtree of an empty root.
+func inSubtree(path, root string) bool {
+	return root == "" || path == root || strings.HasPrefix(path, root+"/")
+}
+
+// WalkWith streams the nodes selected by opts in the order they set.
+func (c Config) WalkWith(opts WalkOptions) chan *Node {
+	ch := make(chan *Node)
+	go func(ch chan *Node) {
+		defer close(ch)
+
+		switch opts.Order {
+		case OrderConcurrent:
+			for n := range c.walkConcurrent() {
+				if opts.Match(n) {
+					ch <- n
+				}
+			}
+		case OrderDepthFirst:
+			c.DepthFirst(func(n *Node) bool {
+				if opts.Match(n) {
+					ch <- n
+				}
+				return true
+			})
+		default:
+			c.BreadthFirst(func(n *Node) bool {
+				if opts.Match(n) {
+					ch <- n
+				}
+				return true
+			})
+		}
+	}(ch)
+	return ch
+}
+
+// DepthFirst calls yield with every node of the tree, each one before its
+// children and siblings sorted by ID, until yield returns false. Being an
+// iter.Seq, it can also be ranged over:
+//
+//	for n := range conf.DepthFirst {
+//		...
+//	}
+func (c Config) DepthFirst(yield func(*Node) bool) {
+	var visit func(nodes []*Node) bool
+	visit = func(nodes []*Node) bool {
+		for _, n := range nodes {
+			if !yield(n) || !visit(sortedByID(n.Children())) {
+				return false
+			}
+		}
+		return true
+	}
+
+	visit(c.roots())
+}
+
+// BreadthFirst calls yield with every node of the tree, level by level and
+// siblings sorted by ID, until yield returns false. Being an iter.Seq, it can
+// also be ranged over:
+//
+//	for n := range conf.BreadthFirst {
+//		...
+//	}
+func (c Config) BreadthFirst(yield func(*Node) bool) {
+	queue := c.roots()
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		if !yield(n) {
+			return
+		}
+		queue = append(queue, sortedByID(n.Children())...)
+	}
+}
+
+// roots returns the pais nodes sorted by ID.
+func (c Config) roots() []*Node {
+	var out []*Node
+	for _, n := range c.paises {
+		out = append(out, n)
+	}
+	return sortedByID(out)
+}
+
+// sortedByID returns a copy of nodes sorted by ID.
+func sortedByID(nodes []*Node) []*Node {
+	out := make([]*Node, len(nodes))
+	copy(out, nodes)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID() < out[j].ID()
+	})
+	return out
+}
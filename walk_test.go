@@ -0,0 +1,83 @@
+package elecciones
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalkWith(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts WalkOptions
+		want int
+	}{
+		{WalkOptions{}, 1 + 19 + 52 + 11 + 8123 + 92},
+		{WalkOptions{Levels: []Level{LevelProvincia}}, 52},
+		{WalkOptions{Root: "ES/CA13", Levels: []Level{LevelProvincia}}, 1},
+		{WalkOptions{Root: "ES/CA13", MaxDepth: 1}, 2},
+		{WalkOptions{Root: "ES/CA13/28"}, 1 + 179 + 21},
+		{WalkOptions{MaxDepth: 1}, 1 + 19},
+	}
+
+	for _, tt := range tests {
+		got := 0
+		for range conf.WalkWith(tt.opts) {
+			got++
+		}
+		if got != tt.want {
+			t.Errorf("WalkWith(%+v): got %d nodes, want %d", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestTraversalOrder(t *testing.T) {
+	conf, err := LoadConfigFromDir("data", DefaultElection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var depthFirst []string
+	for n := range conf.DepthFirst {
+		depthFirst = append(depthFirst, n.Path())
+		if len(depthFirst) == 4 {
+			break
+		}
+	}
+	want := []string{"ES", "ES/CA01", "ES/CA01/04", "ES/CA01/04/04001"}
+	if !reflect.DeepEqual(depthFirst, want) {
+		t.Errorf("DepthFirst: got %v, want %v", depthFirst, want)
+	}
+
+	var breadthFirst []string
+	conf.BreadthFirst(func(n *Node) bool {
+		breadthFirst = append(breadthFirst, n.Path())
+		return len(breadthFirst) < 3
+	})
+	want = []string{"ES", "ES/CA01", "ES/CA02"}
+	if !reflect.DeepEqual(breadthFirst, want) {
+		t.Errorf("BreadthFirst: got %v, want %v", breadthFirst, want)
+	}
+
+	var first, second []string
+	for n := range conf.Walk() {
+		first = append(first, n.Path())
+	}
+	for n := range conf.Walk() {
+		second = append(second, n.Path())
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Walk is not deterministic")
+	}
+
+	concurrent := 0
+	for range conf.WalkWith(WalkOptions{Order: OrderConcurrent}) {
+		concurrent++
+	}
+	if concurrent != len(first) {
+		t.Errorf("concurrent walk: got %d nodes, want %d", concurrent, len(first))
+	}
+}
@@ -0,0 +1,85 @@
+package elecciones
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodeYAML decodes a YAML document into v through its JSON field tags. It
+// only supports what descriptor files need: a flat mapping of strings, plain
+// or quoted, with comments. Plain dates (2016-06-26) are decoded as
+// timestamps, as YAML does.
+func decodeYAML(r io.Reader, v interface{}) error {
+	values := make(map[string]interface{})
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed != text[:len(trimmed)] {
+			return fmt.Errorf("line %d: nested values are not supported", line)
+		}
+
+		i := strings.Index(text, ":")
+		if i < 0 {
+			return fmt.Errorf("line %d: expected key: value", line)
+		}
+		key := strings.TrimSpace(text[:i])
+		if _, ok := values[key]; ok {
+			return fmt.Errorf("line %d: duplicated key %q", line, key)
+		}
+
+		value, err := yamlScalar(strings.TrimSpace(text[i+1:]))
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// yamlScalar returns the value of a YAML scalar.
+func yamlScalar(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	case s == "":
+		return nil, fmt.Errorf("nested values are not supported")
+	case s == "-" || strings.HasPrefix(s, "- ") || strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{"):
+		return nil, fmt.Errorf("only scalar values are supported")
+	}
+
+	// Drop a trailing comment.
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	if s == "null" || s == "~" {
+		return nil, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return s, nil
+}